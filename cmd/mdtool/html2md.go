@@ -18,7 +18,34 @@ var html2mdCmd = &cobra.Command{
 	RunE:  runHTML2MD,
 }
 
+var (
+	html2mdHeadingStyle       string
+	html2mdBulletMarker       string
+	html2mdCodeBlockStyle     string
+	html2mdFence              string
+	html2mdEmDelimiter        string
+	html2mdStrongDelimiter    string
+	html2mdLinkStyle          string
+	html2mdLinkReferenceStyle string
+	html2mdBaseURL            string
+	html2mdSelect             string
+	html2mdExclude            string
+	html2mdReadability        bool
+)
+
 func init() {
+	html2mdCmd.Flags().StringVar(&html2mdHeadingStyle, "heading-style", "", "heading style: atx or setext (default atx)")
+	html2mdCmd.Flags().StringVar(&html2mdBulletMarker, "bullet", "", "bullet list marker: -, + or * (default -)")
+	html2mdCmd.Flags().StringVar(&html2mdCodeBlockStyle, "code-block-style", "", "code block style: indented or fenced (default indented)")
+	html2mdCmd.Flags().StringVar(&html2mdFence, "fence", "", "fence of fenced code blocks: ``` or ~~~ (default ```)")
+	html2mdCmd.Flags().StringVar(&html2mdEmDelimiter, "em-delimiter", "", "emphasis delimiter: _ or * (default _)")
+	html2mdCmd.Flags().StringVar(&html2mdStrongDelimiter, "strong-delimiter", "", "strong emphasis delimiter: ** or __ (default **)")
+	html2mdCmd.Flags().StringVar(&html2mdLinkStyle, "link-style", "", "link style: inlined or referenced (default inlined)")
+	html2mdCmd.Flags().StringVar(&html2mdLinkReferenceStyle, "link-reference-style", "", "style of referenced links: full, collapsed or shortcut (default full)")
+	html2mdCmd.Flags().StringVar(&html2mdBaseURL, "base-url", "", "absolute URL relative links and images are resolved against (default the document's <base href>)")
+	html2mdCmd.Flags().StringVar(&html2mdSelect, "select", "", "CSS selector of the elements to convert, e.g. \"article .content\" (default the whole page)")
+	html2mdCmd.Flags().StringVar(&html2mdExclude, "exclude", "", "CSS selector of elements to leave out, e.g. \"nav, .ads, footer\"")
+	html2mdCmd.Flags().BoolVar(&html2mdReadability, "readability", false, "convert only the main article with its title and byline, as web2md does")
 	rootCmd.AddCommand(html2mdCmd)
 }
 
@@ -60,9 +87,22 @@ func runHTML2MD(cmd *cobra.Command, args []string) error {
 
 	// Convert
 	req := &models.ConvertRequest{
-		Input:   input,
-		Output:  output,
-		Options: make(map[string]interface{}),
+		Input:  input,
+		Output: output,
+		Options: map[string]interface{}{
+			"heading_style":        html2mdHeadingStyle,
+			"bullet_marker":        html2mdBulletMarker,
+			"code_block_style":     html2mdCodeBlockStyle,
+			"fence":                html2mdFence,
+			"em_delimiter":         html2mdEmDelimiter,
+			"strong_delimiter":     html2mdStrongDelimiter,
+			"link_style":           html2mdLinkStyle,
+			"link_reference_style": html2mdLinkReferenceStyle,
+			"base_url":             html2mdBaseURL,
+			"select":               html2mdSelect,
+			"exclude":              html2mdExclude,
+			"readability":          html2mdReadability,
+		},
 	}
 
 	resp := conv.Convert(req)
@@ -18,7 +18,22 @@ var md2pdfCmd = &cobra.Command{
 	RunE:  runMD2PDF,
 }
 
+var (
+	md2pdfColumns      int
+	md2pdfSpanHeadings bool
+	md2pdfIndex        bool
+	md2pdfGlossary     bool
+	md2pdfTheme        string
+	md2pdfPDFA         bool
+)
+
 func init() {
+	md2pdfCmd.Flags().IntVar(&md2pdfColumns, "columns", 1, "number of text columns per page")
+	md2pdfCmd.Flags().BoolVar(&md2pdfSpanHeadings, "span-headings", false, "render headings across all columns")
+	md2pdfCmd.Flags().BoolVar(&md2pdfIndex, "index", false, "append an index of <!-- index: term --> markers and glossary terms")
+	md2pdfCmd.Flags().BoolVar(&md2pdfGlossary, "glossary", false, "append a glossary of all definition list entries")
+	md2pdfCmd.Flags().StringVar(&md2pdfTheme, "theme", "light", "theme providing the heading classes: light, dark, solarized or contrast")
+	md2pdfCmd.Flags().BoolVar(&md2pdfPDFA, "pdfa", false, "produce a validated PDF/A-2b document for archiving")
 	rootCmd.AddCommand(md2pdfCmd)
 }
 
@@ -50,9 +65,16 @@ func runMD2PDF(cmd *cobra.Command, args []string) error {
 
 	// Convert
 	req := &models.ConvertRequest{
-		Input:   input,
-		Output:  output,
-		Options: make(map[string]interface{}),
+		Input:  input,
+		Output: output,
+		Options: map[string]interface{}{
+			"columns":       md2pdfColumns,
+			"span_headings": md2pdfSpanHeadings,
+			"index":         md2pdfIndex,
+			"glossary":      md2pdfGlossary,
+			"theme":         md2pdfTheme,
+			"pdfa":          md2pdfPDFA,
+		},
 	}
 
 	fmt.Fprintf(os.Stderr, "Generating PDF...\n")
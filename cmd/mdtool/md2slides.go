@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/green-creeper/mdtool/internal/converter"
+	"github.com/green-creeper/mdtool/pkg/models"
+	"github.com/spf13/cobra"
+)
+
+var md2slidesCmd = &cobra.Command{
+	Use:   "md2slides [input.md] [output.pdf]",
+	Short: "Convert Markdown to a slide deck PDF",
+	Long: `Generate a 16:9 presentation PDF from a Markdown file.
+
+Each section between thematic breaks (---) becomes a slide. HTML comments
+are collected as speaker notes and can be written to a separate PDF.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runMD2Slides,
+}
+
+var (
+	md2slidesTheme string
+	md2slidesNotes string
+	md2slidesBuild bool
+)
+
+func init() {
+	md2slidesCmd.Flags().StringVar(&md2slidesTheme, "theme", "", "deck theme: light, dark, solarized or contrast (default from the deck or light)")
+	md2slidesCmd.Flags().StringVar(&md2slidesNotes, "notes", "", "write speaker notes to this PDF file")
+	md2slidesCmd.Flags().BoolVar(&md2slidesBuild, "build", false, "reveal bullets one page at a time")
+	rootCmd.AddCommand(md2slidesCmd)
+}
+
+func runMD2Slides(cmd *cobra.Command, args []string) error {
+	conv := converter.NewMD2SlidesConverter()
+
+	inputFile := args[0]
+	var outputFile string
+	if len(args) > 1 {
+		outputFile = args[1]
+	} else {
+		// Default output filename
+		outputFile = inputFile + ".pdf"
+	}
+
+	// Setup input
+	input, err := os.Open(inputFile)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer input.Close()
+
+	// Setup output
+	output, err := os.Create(filepath.Clean(outputFile))
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	defer output.Close()
+
+	// Convert
+	req := &models.ConvertRequest{
+		Input:  input,
+		Output: output,
+		Options: map[string]interface{}{
+			"theme": md2slidesTheme,
+			"build": md2slidesBuild,
+		},
+	}
+
+	// Setup speaker notes output
+	if md2slidesNotes != "" {
+		notes, err := os.Create(filepath.Clean(md2slidesNotes))
+		if err != nil {
+			return fmt.Errorf("failed to create notes file: %w", err)
+		}
+		defer notes.Close()
+		req.Options["notes"] = notes
+	}
+
+	fmt.Fprintf(os.Stderr, "Generating slides...\n")
+	resp := conv.Convert(req)
+	if !resp.Success {
+		return resp.Error
+	}
+
+	fmt.Fprintf(os.Stderr, "✓ Successfully converted %s to %s\n", inputFile, outputFile)
+	fmt.Fprintf(os.Stderr, "  Slides: %s\n", resp.Metadata["slides"])
+	if md2slidesNotes != "" {
+		fmt.Fprintf(os.Stderr, "  Notes: %s\n", md2slidesNotes)
+	}
+
+	return nil
+}
@@ -2,8 +2,10 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/green-creeper/mdtool/internal/converter"
 	"github.com/green-creeper/mdtool/pkg/models"
@@ -11,14 +13,40 @@ import (
 )
 
 var pdf2mdCmd = &cobra.Command{
-	Use:   "pdf2md [input.pdf] [output.md]",
+	Use:   "pdf2md [input.pdf] [output.md | output-dir]",
 	Short: "Convert PDF to Markdown",
 	Long:  `Extract text from a PDF file and convert to Markdown format.`,
 	Args:  cobra.RangeArgs(1, 2),
 	RunE:  runPDF2MD,
 }
 
+var (
+	pdf2mdTables       string
+	pdf2mdContinuous   bool
+	pdf2mdPages        string
+	pdf2mdSplit        string
+	pdf2mdPassword     string
+	pdf2mdPasswordFile string
+	pdf2mdStrict       bool
+	pdf2mdFrontMatter  bool
+	pdf2mdFormat       string
+	pdf2mdJobs         int
+)
+
+// passwordEnv is the environment variable read for the password of encrypted PDFs
+const passwordEnv = "MDTOOL_PDF_PASSWORD"
+
 func init() {
+	pdf2mdCmd.Flags().StringVar(&pdf2mdTables, "tables", "auto", "table detection: off, auto or aggressive")
+	pdf2mdCmd.Flags().BoolVar(&pdf2mdContinuous, "continuous", false, "omit the <!-- page N --> comments and --- separators between pages")
+	pdf2mdCmd.Flags().StringVar(&pdf2mdPages, "pages", "", "pages to convert, e.g. 3-10,15 (default all)")
+	pdf2mdCmd.Flags().StringVar(&pdf2mdSplit, "split", "", "write one file per \"page\" or top-level \"heading\" into the output directory")
+	pdf2mdCmd.Flags().StringVar(&pdf2mdPassword, "password", "", "password of an encrypted PDF (default $"+passwordEnv+")")
+	pdf2mdCmd.Flags().StringVar(&pdf2mdPasswordFile, "password-file", "", "read the password of an encrypted PDF from a file")
+	pdf2mdCmd.Flags().BoolVar(&pdf2mdStrict, "strict", false, "fail if any page cannot be extracted instead of skipping it with a warning")
+	pdf2mdCmd.Flags().BoolVar(&pdf2mdFrontMatter, "front-matter", true, "write the document title, author, subject and keywords as YAML front matter")
+	pdf2mdCmd.Flags().StringVar(&pdf2mdFormat, "format", "markdown", "output format: markdown, or json for the positions of all text runs")
+	pdf2mdCmd.Flags().IntVar(&pdf2mdJobs, "jobs", 0, "number of pages extracted in parallel (default the number of CPUs)")
 	rootCmd.AddCommand(pdf2mdCmd)
 }
 
@@ -31,6 +59,11 @@ func runPDF2MD(cmd *cobra.Command, args []string) error {
 		outputFile = args[1]
 	}
 
+	password, err := pdfPassword()
+	if err != nil {
+		return err
+	}
+
 	// Setup input
 	input, err := os.Open(inputFile)
 	if err != nil {
@@ -38,23 +71,53 @@ func runPDF2MD(cmd *cobra.Command, args []string) error {
 	}
 	defer input.Close()
 
-	// Setup output
-	var output *os.File
-	if outputFile == "" {
+	// Setup output. Images are extracted into an assets directory next to
+	// the Markdown, so there is nowhere to put them when writing to stdout.
+	// Their names start with the output name, so converting several PDFs
+	// into one directory does not overwrite earlier images.
+	var output io.Writer
+	var assetsDir, assetsName string
+	if pdf2mdSplit != "" {
+		if outputFile == "" {
+			return fmt.Errorf("--split requires an output directory")
+		}
+		if err := os.MkdirAll(filepath.Clean(outputFile), 0o755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+		output = io.Discard
+		assetsDir = filepath.Join(filepath.Clean(outputFile), "assets")
+		assetsName = filepath.Base(filepath.Clean(outputFile))
+	} else if outputFile == "" {
 		output = os.Stdout
 	} else {
-		output, err = os.Create(filepath.Clean(outputFile))
+		file, err := os.Create(filepath.Clean(outputFile))
 		if err != nil {
 			return fmt.Errorf("failed to create output file: %w", err)
 		}
-		defer output.Close()
+		defer file.Close()
+		output = file
+		assetsDir = filepath.Join(filepath.Dir(filepath.Clean(outputFile)), "assets")
+		assetsName = strings.TrimSuffix(filepath.Base(outputFile), filepath.Ext(outputFile))
 	}
 
 	// Convert
 	req := &models.ConvertRequest{
-		Input:   input,
-		Output:  output,
-		Options: make(map[string]interface{}),
+		Input:  input,
+		Output: output,
+		Options: map[string]interface{}{
+			"tables":       pdf2mdTables,
+			"continuous":   pdf2mdContinuous,
+			"pages":        pdf2mdPages,
+			"split":        pdf2mdSplit,
+			"output_dir":   outputFile,
+			"assets_dir":   assetsDir,
+			"assets_name":  assetsName,
+			"password":     password,
+			"strict":       pdf2mdStrict,
+			"front_matter": pdf2mdFrontMatter,
+			"format":       pdf2mdFormat,
+			"jobs":         pdf2mdJobs,
+		},
 	}
 
 	fmt.Fprintf(os.Stderr, "Converting PDF...\n")
@@ -62,13 +125,41 @@ func runPDF2MD(cmd *cobra.Command, args []string) error {
 	if !resp.Success {
 		return resp.Error
 	}
+	for _, warning := range resp.Warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
 
 	if outputFile != "" {
 		fmt.Fprintf(os.Stderr, "✓ Successfully converted %s to %s\n", inputFile, outputFile)
 		if pages, ok := resp.Metadata["pages"]; ok {
 			fmt.Fprintf(os.Stderr, "  Pages: %s\n", pages)
 		}
+		if fields, ok := resp.Metadata["form_fields"]; ok {
+			fmt.Fprintf(os.Stderr, "  Form fields: %s\n", fields)
+		}
+		if files, ok := resp.Metadata["files"]; ok {
+			fmt.Fprintf(os.Stderr, "  Files: %s\n", files)
+		}
+		if images, ok := resp.Metadata["images"]; ok && images != "0" {
+			fmt.Fprintf(os.Stderr, "  Images: %s (in %s)\n", images, assetsDir)
+		}
 	}
 
 	return nil
 }
+
+// pdfPassword returns the password given with --password, read from
+// --password-file, or set in the environment, in that order
+func pdfPassword() (string, error) {
+	if pdf2mdPassword != "" {
+		return pdf2mdPassword, nil
+	}
+	if pdf2mdPasswordFile != "" {
+		data, err := os.ReadFile(filepath.Clean(pdf2mdPasswordFile))
+		if err != nil {
+			return "", fmt.Errorf("failed to read password file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+	return os.Getenv(passwordEnv), nil
+}
@@ -1,9 +1,11 @@
 package cmd
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
+	"github.com/green-creeper/mdtool/internal/converter"
 	"github.com/spf13/cobra"
 )
 
@@ -24,7 +26,32 @@ All conversions use pure Go libraries with no CGO or external dependencies.`,
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// Exit codes for PDF input that cannot be opened, so that batch jobs can
+// route such files without parsing error messages
+const (
+	exitPasswordRequired      = 2
+	exitWrongPassword         = 3
+	exitUnsupportedEncryption = 4
+	exitCorruptPDF            = 5
+)
+
+// exitCode returns the process exit code for an error
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, converter.ErrPasswordRequired):
+		return exitPasswordRequired
+	case errors.Is(err, converter.ErrWrongPassword):
+		return exitWrongPassword
+	case errors.Is(err, converter.ErrUnsupportedEncryption):
+		return exitUnsupportedEncryption
+	case errors.Is(err, converter.ErrCorruptPDF):
+		return exitCorruptPDF
+	default:
+		return 1
 	}
 }
 
@@ -18,7 +18,14 @@ var web2mdCmd = &cobra.Command{
 	RunE:  runWeb2MD,
 }
 
+var (
+	web2mdSelect  string
+	web2mdExclude string
+)
+
 func init() {
+	web2mdCmd.Flags().StringVar(&web2mdSelect, "select", "", "CSS selector of the elements to convert instead of the content found by readability")
+	web2mdCmd.Flags().StringVar(&web2mdExclude, "exclude", "", "CSS selector of elements to remove before extraction, e.g. \"nav, .ads, footer\"")
 	rootCmd.AddCommand(web2mdCmd)
 }
 
@@ -49,7 +56,9 @@ func runWeb2MD(cmd *cobra.Command, args []string) error {
 		Input:  nil, // Not used for web scraping
 		Output: output,
 		Options: map[string]interface{}{
-			"url": url,
+			"url":     url,
+			"select":  web2mdSelect,
+			"exclude": web2mdExclude,
 		},
 	}
 
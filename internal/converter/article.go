@@ -0,0 +1,105 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	readability "github.com/go-shiori/go-readability"
+)
+
+// Article is the main content of an HTML page with its title and byline
+type Article struct {
+	Title   string
+	Byline  string
+	Content string // HTML
+}
+
+// ExtractArticle extracts the main content of an HTML page with readability.
+// Relative links are resolved against pageURL, which may be nil. Elements
+// matching exclude are removed first; with a selector, the matching elements
+// are the content and readability only provides the title and byline.
+func ExtractArticle(page []byte, pageURL *url.URL, selector, exclude string) (*Article, error) {
+	// Narrow the page down with CSS selectors before extracting the article
+	var doc *goquery.Document
+	if selector != "" || exclude != "" {
+		var err error
+		doc, err = goquery.NewDocumentFromReader(bytes.NewReader(page))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		}
+		if pageURL != nil {
+			ResolveURLs(doc, pageURL)
+		}
+		if err := FilterHTML(doc, selector, exclude); err != nil {
+			return nil, err
+		}
+		filtered, err := doc.Html()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		}
+		page = []byte(filtered)
+	}
+
+	// Parse with readability to extract main content
+	article, err := readability.FromReader(bytes.NewReader(page), pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse article content: %w", err)
+	}
+
+	content := article.Content
+	if selector != "" {
+		if content, err = doc.Find("body").Html(); err != nil {
+			return nil, fmt.Errorf("failed to parse HTML: %w", err)
+		}
+	}
+
+	return &Article{
+		Title:   article.Title,
+		Byline:  article.Byline,
+		Content: content,
+	}, nil
+}
+
+// ArticleMarkdown prefixes the Markdown of an article with its title, byline
+// and source. The source line is left out if source is empty.
+func ArticleMarkdown(article *Article, source, markdown string) string {
+	var builder strings.Builder
+	// Calculate total size:
+	// "# " (2) + title + "\n\n" (2)
+	// optional: "*By " (4) + byline + "*\n\n" (3)
+	// optional: "*Source: [" (10) + url + "](" (2) + url + ")*\n\n" (4)
+	// "---\n\n" (5) + markdown
+	size := 2 + len(article.Title) + 2 + 5 + len(markdown)
+	if article.Byline != "" {
+		size += 4 + len(article.Byline) + 3
+	}
+	if source != "" {
+		size += 10 + len(source) + 2 + len(source) + 4
+	}
+	builder.Grow(size)
+
+	builder.WriteString("# ")
+	builder.WriteString(article.Title)
+	builder.WriteString("\n\n")
+
+	if article.Byline != "" {
+		builder.WriteString("*By ")
+		builder.WriteString(article.Byline)
+		builder.WriteString("*\n\n")
+	}
+
+	if source != "" {
+		builder.WriteString("*Source: [")
+		builder.WriteString(source)
+		builder.WriteString("](")
+		builder.WriteString(source)
+		builder.WriteString(")*\n\n")
+	}
+	builder.WriteString("---\n\n")
+	builder.WriteString(markdown)
+
+	return builder.String()
+}
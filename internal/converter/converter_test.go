@@ -26,6 +26,13 @@ func TestConverters(t *testing.T) {
 			expectedSrc:   "markdown",
 			expectedTgt:   "pdf",
 		},
+		{
+			name:          "Markdown to Slides",
+			converter:     NewMD2SlidesConverter(),
+			expectedName:  "Markdown to Slides Converter",
+			expectedSrc:   "markdown",
+			expectedTgt:   "pdf",
+		},
 		{
 			name:          "PDF to Markdown",
 			converter:     NewPDF2MDConverter(),
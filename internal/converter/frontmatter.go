@@ -0,0 +1,119 @@
+package converter
+
+import (
+	"bytes"
+	"strings"
+
+	"codeberg.org/go-pdf/fpdf"
+)
+
+// splitFrontMatter separates a leading YAML front matter block delimited by
+// --- lines from the Markdown body. Only flat "key: value" pairs and simple
+// lists are understood; list values are joined with ", ". When the block
+// holds anything else, or no "key: value" pair at all, it is treated as
+// regular Markdown.
+func splitFrontMatter(source []byte) (map[string]string, []byte) {
+	text := string(bytes.TrimPrefix(source, []byte("\xef\xbb\xbf")))
+	if !strings.HasPrefix(text, "---\n") && !strings.HasPrefix(text, "---\r\n") {
+		return nil, source
+	}
+
+	lines := strings.SplitAfter(text, "\n")
+	meta := make(map[string]string)
+	offset := len(lines[0])
+	lastKey := ""
+	for _, line := range lines[1:] {
+		offset += len(line)
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "---" || trimmed == "...":
+			// A heading between two rules, such as a slide, is not front matter
+			if len(meta) == 0 {
+				return nil, source
+			}
+			return meta, []byte(text[offset:])
+
+		case trimmed == "" || strings.HasPrefix(trimmed, "#"):
+			continue
+
+		case strings.HasPrefix(trimmed, "- ") && lastKey != "":
+			item := unquote(strings.TrimSpace(trimmed[2:]))
+			if meta[lastKey] != "" {
+				item = meta[lastKey] + ", " + item
+			}
+			meta[lastKey] = item
+
+		default:
+			key, value, ok := strings.Cut(trimmed, ":")
+			if !ok || strings.ContainsAny(key, " \t") {
+				return nil, source
+			}
+			lastKey = strings.ToLower(key)
+			value = strings.TrimSpace(value)
+			if strings.HasPrefix(value, "[") && strings.HasSuffix(value, "]") {
+				var items []string
+				for _, item := range strings.Split(value[1:len(value)-1], ",") {
+					items = append(items, unquote(strings.TrimSpace(item)))
+				}
+				value = strings.Join(items, ", ")
+			}
+			meta[lastKey] = unquote(value)
+		}
+	}
+
+	// No closing delimiter, so this is not front matter
+	return nil, source
+}
+
+// unquote strips matching single or double quotes around a YAML scalar
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// docInfo holds the document information fields taken from front matter
+type docInfo struct {
+	Title, Author, Subject, Keywords, Lang string
+}
+
+// documentInfo maps front matter fields to document information, accepting
+// description for the subject and tags for the keywords
+func documentInfo(meta map[string]string) docInfo {
+	info := docInfo{
+		Title:    meta["title"],
+		Author:   meta["author"],
+		Subject:  meta["subject"],
+		Keywords: meta["keywords"],
+		Lang:     meta["lang"],
+	}
+	if info.Subject == "" {
+		info.Subject = meta["description"]
+	}
+	if info.Keywords == "" {
+		info.Keywords = meta["tags"]
+	}
+	return info
+}
+
+// applyDocumentInfo copies the front matter fields to the PDF document information
+func applyDocumentInfo(pdf *fpdf.Fpdf, meta map[string]string) {
+	info := documentInfo(meta)
+	if info.Title != "" {
+		pdf.SetTitle(info.Title, true)
+	}
+	if info.Author != "" {
+		pdf.SetAuthor(info.Author, true)
+	}
+	if info.Subject != "" {
+		pdf.SetSubject(info.Subject, true)
+	}
+	if info.Keywords != "" {
+		pdf.SetKeywords(info.Keywords, true)
+	}
+	if info.Lang != "" {
+		pdf.SetLang(info.Lang)
+	}
+}
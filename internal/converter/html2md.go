@@ -1,11 +1,15 @@
 package converter
 
 import (
+	"bytes"
 	"fmt"
 	"io"
+	"net/url"
+	"strings"
 
 	md "github.com/JohannesKaufmann/html-to-markdown"
 	"github.com/JohannesKaufmann/html-to-markdown/plugin"
+	"github.com/PuerkitoBio/goquery"
 	"github.com/green-creeper/mdtool/pkg/models"
 )
 
@@ -14,15 +18,64 @@ type HTML2MDConverter struct {
 	converter *md.Converter
 }
 
+// markdownStyles are the html-to-markdown output options that can be set in
+// ConvertRequest.Options, with the values they accept. Options left empty
+// keep the library defaults.
+var markdownStyles = []struct {
+	key    string
+	values []string
+	set    func(o *md.Options, value string)
+}{
+	{"heading_style", []string{"atx", "setext"}, func(o *md.Options, v string) { o.HeadingStyle = v }},
+	{"bullet_marker", []string{"-", "+", "*"}, func(o *md.Options, v string) { o.BulletListMarker = v }},
+	{"code_block_style", []string{"indented", "fenced"}, func(o *md.Options, v string) { o.CodeBlockStyle = v }},
+	{"fence", []string{"```", "~~~"}, func(o *md.Options, v string) { o.Fence = v }},
+	{"em_delimiter", []string{"_", "*"}, func(o *md.Options, v string) { o.EmDelimiter = v }},
+	{"strong_delimiter", []string{"**", "__"}, func(o *md.Options, v string) { o.StrongDelimiter = v }},
+	{"link_style", []string{"inlined", "referenced"}, func(o *md.Options, v string) { o.LinkStyle = v }},
+	{"link_reference_style", []string{"full", "collapsed", "shortcut"}, func(o *md.Options, v string) { o.LinkReferenceStyle = v }},
+}
+
 // NewHTML2MDConverter creates a new HTML to Markdown converter
 func NewHTML2MDConverter() *HTML2MDConverter {
-	converter := md.NewConverter("", true, nil)
-	converter.Use(plugin.Table())
 	return &HTML2MDConverter{
-		converter: converter,
+		converter: newMarkdownConverter(nil),
 	}
 }
 
+// newMarkdownConverter creates an html-to-markdown converter with GFM tables
+func newMarkdownConverter(options *md.Options) *md.Converter {
+	converter := md.NewConverter("", true, options)
+	converter.Use(plugin.Table())
+	return converter
+}
+
+// markdownOptions returns the output style set in the request, or nil if
+// none is set
+func markdownOptions(req *models.ConvertRequest) (*md.Options, error) {
+	var options *md.Options
+	for _, style := range markdownStyles {
+		value := req.StringOption(style.key, "")
+		if value == "" {
+			continue
+		}
+		valid := false
+		for _, v := range style.values {
+			valid = valid || v == value
+		}
+		if !valid {
+			n := len(style.values)
+			return nil, fmt.Errorf("invalid %s %q (must be %s or %s)", strings.ReplaceAll(style.key, "_", " "), value,
+				strings.Join(style.values[:n-1], ", "), style.values[n-1])
+		}
+		if options == nil {
+			options = &md.Options{}
+		}
+		style.set(options, value)
+	}
+	return options, nil
+}
+
 // Convert converts HTML to Markdown
 func (c *HTML2MDConverter) Convert(req *models.ConvertRequest) *models.ConvertResponse {
 	// Read HTML content
@@ -34,8 +87,20 @@ func (c *HTML2MDConverter) Convert(req *models.ConvertRequest) *models.ConvertRe
 		}
 	}
 
-	// Convert to Markdown
-	markdown, err := c.converter.ConvertString(string(htmlBytes))
+	// Requests setting an output style get a converter of their own
+	options, err := markdownOptions(req)
+	if err != nil {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   err,
+		}
+	}
+	converter := c.converter
+	if options != nil {
+		converter = newMarkdownConverter(options)
+	}
+
+	doc, err := goquery.NewDocumentFromReader(bytes.NewReader(htmlBytes))
 	if err != nil {
 		return &models.ConvertResponse{
 			Success: false,
@@ -43,6 +108,35 @@ func (c *HTML2MDConverter) Convert(req *models.ConvertRequest) *models.ConvertRe
 		}
 	}
 
+	// Relative links and images are made absolute when there is a base URL
+	base, err := baseURL(req.StringOption("base_url", ""), doc)
+	if err != nil {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   err,
+		}
+	}
+
+	// Readability keeps only the main article, as web2md does
+	if req.BoolOption("readability", false) {
+		return c.convertArticle(req, converter, htmlBytes, base)
+	}
+
+	if base != nil {
+		ResolveURLs(doc, base)
+	}
+
+	// Only the selected parts of the page are converted
+	if err := FilterHTML(doc, req.StringOption("select", ""), req.StringOption("exclude", "")); err != nil {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   err,
+		}
+	}
+
+	// Convert to Markdown
+	markdown := converter.Convert(doc.Selection)
+
 	// Write output
 	_, err = req.Output.Write([]byte(markdown))
 	if err != nil {
@@ -60,6 +154,70 @@ func (c *HTML2MDConverter) Convert(req *models.ConvertRequest) *models.ConvertRe
 	}
 }
 
+// convertArticle converts the main article of a page with a header of its
+// title, byline and, if there is a base URL, source
+func (c *HTML2MDConverter) convertArticle(req *models.ConvertRequest, converter *md.Converter, htmlBytes []byte, base *url.URL) *models.ConvertResponse {
+	article, err := ExtractArticle(htmlBytes, base, req.StringOption("select", ""), req.StringOption("exclude", ""))
+	if err != nil {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   err,
+		}
+	}
+
+	markdown, err := converter.ConvertString(article.Content)
+	if err != nil {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   fmt.Errorf("failed to convert HTML to Markdown: %w", err),
+		}
+	}
+
+	var source string
+	if base != nil {
+		source = base.String()
+	}
+	_, err = req.Output.Write([]byte(ArticleMarkdown(article, source, markdown)))
+	if err != nil {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   fmt.Errorf("failed to write Markdown output: %w", err),
+		}
+	}
+
+	return &models.ConvertResponse{
+		Success: true,
+		Metadata: map[string]string{
+			"converter": "html2md",
+			"title":     article.Title,
+			"byline":    article.Byline,
+		},
+	}
+}
+
+// baseURL returns the URL relative links are resolved against: the base_url
+// option, or else the href of the document's <base> element. It returns nil
+// if there is neither.
+func baseURL(option string, doc *goquery.Document) (*url.URL, error) {
+	if option != "" {
+		base, err := url.Parse(option)
+		if err != nil || !base.IsAbs() {
+			return nil, fmt.Errorf("invalid base URL %q (must be an absolute URL)", option)
+		}
+		return base, nil
+	}
+
+	// Relative or malformed <base> elements are ignored
+	href, ok := doc.Find("base[href]").First().Attr("href")
+	if !ok {
+		return nil, nil
+	}
+	if base, err := url.Parse(strings.TrimSpace(href)); err == nil && base.IsAbs() {
+		return base, nil
+	}
+	return nil, nil
+}
+
 // Name returns the converter name
 func (c *HTML2MDConverter) Name() string {
 	return "HTML to Markdown Converter"
@@ -54,3 +54,158 @@ func TestHTML2MDConverter_Convert(t *testing.T) {
 		})
 	}
 }
+
+func TestHTML2MDConverter_Styles(t *testing.T) {
+	html := `<h2>Setup</h2><ul><li>One</li></ul><p><em>a</em> <strong>b</strong> <a href="https://example.com">site</a></p><pre><code>go build</code></pre>`
+	options := map[string]interface{}{
+		"heading_style":    "setext",
+		"bullet_marker":    "*",
+		"code_block_style": "fenced",
+		"fence":            "~~~",
+		"em_delimiter":     "*",
+		"strong_delimiter": "__",
+		"link_style":       "referenced",
+	}
+
+	c := NewHTML2MDConverter()
+	var output bytes.Buffer
+	resp := c.Convert(&models.ConvertRequest{Input: strings.NewReader(html), Output: &output, Options: options})
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+	for _, want := range []string{"Setup\n-----", "* One", "~~~\ngo build\n~~~", "*a* __b__", "[site][1]", "[1]: https://example.com"} {
+		if !strings.Contains(output.String(), want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output.String())
+		}
+	}
+
+	// The default converter is not affected by the options of other requests
+	output.Reset()
+	c.Convert(&models.ConvertRequest{Input: strings.NewReader(html), Output: &output})
+	if !strings.Contains(output.String(), "## Setup") || !strings.Contains(output.String(), "- One") {
+		t.Errorf("Expected default styles, got:\n%s", output.String())
+	}
+
+	resp = c.Convert(&models.ConvertRequest{
+		Input:   strings.NewReader(html),
+		Output:  &output,
+		Options: map[string]interface{}{"bullet_marker": "•"},
+	})
+	if resp.Success || resp.Error.Error() != `invalid bullet marker "•" (must be -, + or *)` {
+		t.Errorf("Expected an invalid bullet marker error, got %v", resp.Error)
+	}
+}
+
+func TestHTML2MDConverter_BaseURL(t *testing.T) {
+	c := NewHTML2MDConverter()
+	convert := func(html string, options map[string]interface{}) (string, error) {
+		var output bytes.Buffer
+		resp := c.Convert(&models.ConvertRequest{Input: strings.NewReader(html), Output: &output, Options: options})
+		return output.String(), resp.Error
+	}
+
+	html := `<p><a href="../img/a.png">up</a> <a href="/docs/x">root</a> <a href="#top">top</a> <a href="mailto:a@example.com">mail</a> <img src="pic.png" alt="pic"></p>`
+	output, err := convert(html, map[string]interface{}{"base_url": "https://example.com/guide/intro/page.html"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"[up](https://example.com/guide/img/a.png)",
+		"[root](https://example.com/docs/x)",
+		"[top](#top)",
+		"[mail](mailto:a@example.com)",
+		"![pic](https://example.com/guide/intro/pic.png)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+
+	// The document's <base> element is the default, and the option overrides it
+	doc := `<html><head><base href="https://docs.example.org/v2/"></head><body><a href="setup">setup</a></body></html>`
+	if output, _ := convert(doc, nil); !strings.Contains(output, "[setup](https://docs.example.org/v2/setup)") {
+		t.Errorf("Expected the link resolved against <base>, got:\n%s", output)
+	}
+	if output, _ := convert(doc, map[string]interface{}{"base_url": "https://mirror.example.net/"}); !strings.Contains(output, "[setup](https://mirror.example.net/setup)") {
+		t.Errorf("Expected the link resolved against the base URL option, got:\n%s", output)
+	}
+
+	// Without a base URL links stay relative
+	if output, _ := convert(html, nil); !strings.Contains(output, "[up](../img/a.png)") {
+		t.Errorf("Expected relative links to be kept, got:\n%s", output)
+	}
+
+	if _, err := convert(html, map[string]interface{}{"base_url": "guide/"}); err == nil || err.Error() != `invalid base URL "guide/" (must be an absolute URL)` {
+		t.Errorf("Expected an invalid base URL error, got %v", err)
+	}
+}
+
+func TestHTML2MDConverter_SelectExclude(t *testing.T) {
+	html := `<html><body><nav><a href="/">Home</a></nav>
+<article><div class="content"><h1>Title</h1><p>Body text.</p><div class="ads">Buy now</div></div><aside>Related</aside></article>
+<footer>Copyright</footer></body></html>`
+	c := NewHTML2MDConverter()
+	convert := func(options map[string]interface{}) (string, error) {
+		var output bytes.Buffer
+		resp := c.Convert(&models.ConvertRequest{Input: strings.NewReader(html), Output: &output, Options: options})
+		return output.String(), resp.Error
+	}
+
+	output, err := convert(map[string]interface{}{"select": "article .content, .content p", "exclude": "nav, .ads, footer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# Title\n\nBody text."; output != want {
+		t.Errorf("Expected only the selected content %q, got %q", want, output)
+	}
+
+	output, err = convert(map[string]interface{}{"exclude": "nav, .ads, footer"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, gone := range []string{"Home", "Buy now", "Copyright"} {
+		if strings.Contains(output, gone) {
+			t.Errorf("Expected %q to be excluded, got:\n%s", gone, output)
+		}
+	}
+	if !strings.Contains(output, "Related") {
+		t.Errorf("Expected content outside the excluded elements, got:\n%s", output)
+	}
+
+	if _, err := convert(map[string]interface{}{"select": "main"}); err == nil || err.Error() != `no elements match selector "main"` {
+		t.Errorf("Expected a no match error, got %v", err)
+	}
+	if _, err := convert(map[string]interface{}{"exclude": "nav["}); err == nil || !strings.HasPrefix(err.Error(), `invalid CSS selector "nav["`) {
+		t.Errorf("Expected an invalid selector error, got %v", err)
+	}
+}
+
+func TestHTML2MDConverter_Readability(t *testing.T) {
+	html := `<html><head><title>Release Notes</title><meta name="author" content="The Team"></head><body>
+<nav><a href="/">Home</a> <a href="/blog">Blog</a></nav>
+<article><h1>Version 2.0</h1><p>This release rewrites the parser and makes conversions twice as fast on large documents.</p>
+<p>Upgrading needs no changes to existing configuration files.</p></article>
+<footer>Copyright</footer></body></html>`
+
+	var output bytes.Buffer
+	resp := NewHTML2MDConverter().Convert(&models.ConvertRequest{
+		Input:   strings.NewReader(html),
+		Output:  &output,
+		Options: map[string]interface{}{"readability": true},
+	})
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+
+	// Without a base URL there is no source line
+	result := output.String()
+	if !strings.HasPrefix(result, "# Release Notes\n\n*By The Team*\n\n---\n\n") {
+		t.Errorf("Expected a title and byline header, got:\n%s", result)
+	}
+	if !strings.Contains(result, "twice as fast") || strings.Contains(result, "Copyright") || strings.Contains(result, "Blog") {
+		t.Errorf("Expected only the main article, got:\n%s", result)
+	}
+	if resp.Metadata["title"] != "Release Notes" || resp.Metadata["byline"] != "The Team" {
+		t.Errorf("Expected the title and byline in metadata, got %v", resp.Metadata)
+	}
+}
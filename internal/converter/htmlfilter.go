@@ -0,0 +1,61 @@
+package converter
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/andybalholm/cascadia"
+)
+
+// FilterHTML removes the elements of a document matching the exclude CSS
+// selector and, if selector is set, reduces the body to the elements
+// matching it, in document order. Elements nested in another match are not
+// repeated. The head of the document is kept.
+func FilterHTML(doc *goquery.Document, selector, exclude string) error {
+	if exclude != "" {
+		matcher, err := cascadia.Compile(exclude)
+		if err != nil {
+			return fmt.Errorf("invalid CSS selector %q: %w", exclude, err)
+		}
+		doc.FindMatcher(matcher).Remove()
+	}
+	if selector == "" {
+		return nil
+	}
+
+	matcher, err := cascadia.Compile(selector)
+	if err != nil {
+		return fmt.Errorf("invalid CSS selector %q: %w", selector, err)
+	}
+	selected := doc.FindMatcher(matcher).FilterFunction(func(_ int, s *goquery.Selection) bool {
+		return s.ParentsMatcher(matcher).Length() == 0
+	})
+	if selected.Length() == 0 {
+		return fmt.Errorf("no elements match selector %q", selector)
+	}
+
+	body := doc.Find("body")
+	body.Contents().Remove()
+	body.AppendSelection(selected)
+	return nil
+}
+
+// ResolveURLs rewrites the link and image URLs of a document to absolute
+// URLs. Links to fragments of the same page are left unchanged.
+func ResolveURLs(doc *goquery.Document, base *url.URL) {
+	resolve := func(selector, attr string) {
+		doc.Find(selector).Each(func(_ int, s *goquery.Selection) {
+			raw := strings.TrimSpace(s.AttrOr(attr, ""))
+			if raw == "" || strings.HasPrefix(raw, "#") {
+				return
+			}
+			if ref, err := url.Parse(raw); err == nil {
+				s.SetAttr(attr, base.ResolveReference(ref).String())
+			}
+		})
+	}
+	resolve("a[href]", "href")
+	resolve("img[src]", "src")
+}
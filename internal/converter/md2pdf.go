@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"regexp"
+	"strconv"
 	"strings"
 
 	"codeberg.org/go-pdf/fpdf"
@@ -41,6 +42,12 @@ var emojiReplacements = map[string]string{
 	"👎": "[-]",
 }
 
+// directiveRegex matches directives written as HTML comments, e.g. <!-- columns: 2 -->
+var directiveRegex = regexp.MustCompile(`(?s)^<!--\s*([A-Za-z-]+)\s*:\s*(.*?)\s*-->$`)
+
+// commentRegex matches a complete HTML comment
+var commentRegex = regexp.MustCompile(`(?s)^<!--(.*?)-->$`)
+
 var emojiReplacer *strings.Replacer
 
 func init() {
@@ -71,6 +78,21 @@ func NewMD2PDFConverter() *MD2PDFConverter {
 type pdfRenderer struct {
 	pdf    *fpdf.Fpdf
 	source []byte
+
+	// Multi-column layout state, see md2pdf_columns.go
+	columns      int     // number of columns in the current region
+	column       int     // index of the column currently being filled
+	regionTop    float64 // Y where the columns of the current region start
+	spanHeadings bool    // render headings across all columns
+	pageLeft     float64 // page margins the columns are laid out between
+	pageRight    float64
+
+	// Index terms and glossary entries, see md2pdf_index.go
+	index *bookIndex
+
+	// Link targets of heading ids and the heading classes of the theme
+	anchors        map[string]int
+	headingClasses map[string]headingStyle
 }
 
 // Convert converts Markdown to PDF
@@ -84,51 +106,127 @@ func (c *MD2PDFConverter) Convert(req *models.ConvertRequest) *models.ConvertRes
 		}
 	}
 
+	themeName := req.StringOption("theme", "light")
+	docTheme, ok := themes[themeName]
+	if !ok {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   fmt.Errorf("unknown theme %q (available: %s)", themeName, strings.Join(themeNames(), ", ")),
+		}
+	}
+
+	columns := req.IntOption("columns", 1)
+	if columns < 1 || columns > maxColumns {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   fmt.Errorf("invalid column count %d (must be between 1 and %d)", columns, maxColumns),
+		}
+	}
+
 	// Create PDF with embedded Unicode fonts
 	pdf := fpdf.New("P", "mm", "A4", "")
-
-	// Add embedded DejaVu fonts for full Unicode support
-	pdf.AddUTF8FontFromBytes("DejaVu", "", dejaVuSansFont)
-	pdf.AddUTF8FontFromBytes("DejaVu", "B", dejaVuSansBoldFont)
-	pdf.AddUTF8FontFromBytes("DejaVuMono", "", dejaVuSansMonoFont)
-	pdf.AddUTF8FontFromBytes("DejaVuMono", "B", dejaVuSansMonoBoldFont)
+	addFonts(pdf)
+
+	// PDF/A documents take their metadata from the front matter
+	body := mdBytes
+	pdfa := req.BoolOption("pdfa", false)
+	if pdfa {
+		var meta map[string]string
+		meta, body = splitFrontMatter(mdBytes)
+		preparePDFA(pdf, meta)
+	}
 
 	pdf.AddPage()
 	pdf.SetFont("DejaVu", "", 12)
 
-	// Parse Markdown with goldmark including GFM table extension
-	md := goldmark.New(
-		goldmark.WithExtensions(extension.GFM),
-		goldmark.WithParserOptions(
-			parser.WithAutoHeadingID(),
-		),
-	)
-
-	reader := text.NewReader(mdBytes)
-	doc := md.Parser().Parse(reader)
+	doc := parseMarkdown(body)
 
 	// Render the AST to PDF
 	renderer := &pdfRenderer{
-		pdf:    pdf,
-		source: mdBytes,
+		pdf:            pdf,
+		source:         body,
+		spanHeadings:   req.BoolOption("span_headings", false),
+		index:          newBookIndex(),
+		headingClasses: docTheme.headingStyles(),
 	}
+	renderer.collectAnchors(doc)
+	renderer.setupColumns(columns)
 	renderer.renderNode(doc)
 
-	// Write PDF to output
-	err = pdf.Output(req.Output)
+	// Back matter needs the page numbers collected during layout
+	if req.BoolOption("glossary", false) {
+		renderer.renderGlossary()
+	}
+	if req.BoolOption("index", false) {
+		renderer.renderIndex()
+	}
+
+	metadata := map[string]string{
+		"converter":      "md2pdf",
+		"index_terms":    fmt.Sprintf("%d", len(renderer.index.terms)),
+		"glossary_terms": fmt.Sprintf("%d", len(renderer.index.glossary)),
+	}
+
+	if !pdfa {
+		// Write PDF to output
+		err = pdf.Output(req.Output)
+		if err != nil {
+			return &models.ConvertResponse{
+				Success: false,
+				Error:   fmt.Errorf("failed to write PDF: %w", err),
+			}
+		}
+		return &models.ConvertResponse{Success: true, Metadata: metadata}
+	}
+
+	// PDF/A output is post-processed and validated before anything is written
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   fmt.Errorf("failed to generate PDF: %w", err),
+		}
+	}
+	archive, err := toPDFA(buf.Bytes())
+	if err == nil {
+		err = validatePDFA(archive)
+	}
 	if err != nil {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   err,
+		}
+	}
+	if _, err := req.Output.Write(archive); err != nil {
 		return &models.ConvertResponse{
 			Success: false,
 			Error:   fmt.Errorf("failed to write PDF: %w", err),
 		}
 	}
 
-	return &models.ConvertResponse{
-		Success: true,
-		Metadata: map[string]string{
-			"converter": "md2pdf",
-		},
-	}
+	metadata["pdfa"] = "2b"
+	return &models.ConvertResponse{Success: true, Metadata: metadata}
+}
+
+// addFonts registers the embedded DejaVu fonts for full Unicode support
+func addFonts(pdf *fpdf.Fpdf) {
+	pdf.AddUTF8FontFromBytes("DejaVu", "", dejaVuSansFont)
+	pdf.AddUTF8FontFromBytes("DejaVu", "B", dejaVuSansBoldFont)
+	pdf.AddUTF8FontFromBytes("DejaVuMono", "", dejaVuSansMonoFont)
+	pdf.AddUTF8FontFromBytes("DejaVuMono", "B", dejaVuSansMonoBoldFont)
+}
+
+// parseMarkdown parses Markdown with goldmark including the GFM and definition list extensions
+func parseMarkdown(source []byte) ast.Node {
+	md := goldmark.New(
+		goldmark.WithExtensions(extension.GFM, extension.DefinitionList),
+		goldmark.WithParserOptions(
+			parser.WithAutoHeadingID(),
+			parser.WithAttribute(),
+		),
+	)
+
+	return md.Parser().Parse(text.NewReader(source))
 }
 
 // renderNode recursively renders AST nodes to PDF
@@ -164,6 +262,12 @@ func (r *pdfRenderer) renderNode(n ast.Node) {
 	case *extast.Table:
 		r.renderTable(node)
 
+	case *ast.HTMLBlock:
+		r.renderHTMLBlock(node)
+
+	case *extast.DefinitionList:
+		r.renderDefinitionList(node)
+
 	default:
 		// For other block nodes, try to render children
 		if n.HasChildren() {
@@ -174,7 +278,7 @@ func (r *pdfRenderer) renderNode(n ast.Node) {
 	}
 }
 
-// renderHeading renders a heading with appropriate font size
+// renderHeading renders a heading with appropriate font size and its class styles
 func (r *pdfRenderer) renderHeading(node *ast.Heading) {
 	sizes := map[int]float64{1: 20, 2: 17, 3: 14, 4: 12, 5: 11, 6: 10}
 	size := sizes[node.Level]
@@ -182,20 +286,214 @@ func (r *pdfRenderer) renderHeading(node *ast.Heading) {
 		size = 12
 	}
 
+	style := r.headingStyle(node)
+	if style.Scale > 0 {
+		size *= style.Scale
+	}
+
+	// Chapter-style headings start a new page unless the page is still empty
+	_, top, _, _ := r.pdf.GetMargins()
+	if style.PageBreak && (r.column > 0 || r.pdf.GetY() > top) {
+		r.newPage()
+	}
+
+	// Spanning headings close the current column region and open a new one
+	// below. Columns are not balanced, so once text has flowed past the
+	// first column there is no room below it and the heading stays in its
+	// column rather than leaving the rest of the page blank.
+	if r.spanHeadings && r.columns > 1 && r.column == 0 {
+		columns := r.columns
+		r.startRegion(1)
+		defer r.startRegion(columns)
+	}
+
+	// Keep the anchor on the same page as the heading text
+	r.ensureSpace(size * 0.5)
+	r.markIndexTerms(node, size*0.5)
+	r.markHeading(node)
+
 	r.pdf.SetFont("DejaVu", "B", size)
+	if style.Color != nil {
+		setTextColor(r.pdf, *style.Color)
+	}
 	text := r.extractText(node)
-	r.pdf.MultiCell(0, size*0.5, text, "", "", false)
+	r.pdf.MultiCell(0, size*0.5, text, "", style.Align, false)
 	r.pdf.Ln(3)
 	r.pdf.SetFont("DejaVu", "", 12)
+	r.pdf.SetTextColor(0, 0, 0)
+}
+
+// headingStyle merges the theme styles of the classes set on a heading, e.g. {.accent .center}
+func (r *pdfRenderer) headingStyle(node *ast.Heading) headingStyle {
+	var merged headingStyle
+	for _, class := range strings.Fields(attributeString(node, "class")) {
+		style, ok := r.headingClasses[class]
+		if !ok {
+			continue
+		}
+		if style.Color != nil {
+			merged.Color = style.Color
+		}
+		if style.Align != "" {
+			merged.Align = style.Align
+		}
+		if style.Scale > 0 {
+			merged.Scale = style.Scale
+		}
+		merged.PageBreak = merged.PageBreak || style.PageBreak
+	}
+	return merged
+}
+
+// markHeading sets the link target of the heading id and records {.index} headings
+func (r *pdfRenderer) markHeading(node *ast.Heading) {
+	if link, ok := r.anchors[attributeString(node, "id")]; ok {
+		r.pdf.SetLink(link, r.pdf.GetY(), r.pdf.PageNo())
+	}
+
+	for _, class := range strings.Fields(attributeString(node, "class")) {
+		if class != "index" {
+			continue
+		}
+		term := attributeString(node, "term")
+		if term == "" {
+			term = r.extractText(node)
+		}
+		r.addIndexTerms(term)
+	}
+}
+
+// collectAnchors creates a link target for every heading id so links can point forward
+func (r *pdfRenderer) collectAnchors(doc ast.Node) {
+	r.anchors = make(map[string]int)
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		if id := attributeString(heading, "id"); id != "" {
+			r.anchors[id] = r.pdf.AddLink()
+		}
+		return ast.WalkSkipChildren, nil
+	})
+}
+
+// attributeString returns an attribute set with {#id .class key=value}, or "" if it is unset
+func attributeString(node ast.Node, name string) string {
+	value, ok := node.AttributeString(name)
+	if !ok {
+		return ""
+	}
+	switch v := value.(type) {
+	case []byte:
+		return string(v)
+	case string:
+		return v
+	}
+	return ""
+}
+
+// newPage continues on a new page with the current number of columns
+func (r *pdfRenderer) newPage() {
+	columns := r.columns
+	r.column = 0
+	r.pdf.AddPage()
+	r.startRegion(columns)
 }
 
 // renderParagraph renders a paragraph with inline formatting
 func (r *pdfRenderer) renderParagraph(node *ast.Paragraph) {
+	// Paragraphs with links are written inline so the links stay clickable,
+	// and paragraphs with index terms so each term points to the page its
+	// marker ends up on
+	if hasLinks(node) || len(indexDirectives(node, r.source)) > 0 {
+		r.ensureSpace(6)
+		r.writeInline(node, 6)
+		r.pdf.Ln(6)
+		r.pdf.Ln(3)
+		return
+	}
+
 	text := r.extractFormattedText(node)
 	r.pdf.MultiCell(0, 6, text, "", "", false)
 	r.pdf.Ln(3)
 }
 
+// hasLinks reports whether a block contains links
+func hasLinks(node ast.Node) bool {
+	found := false
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		switch n.(type) {
+		case *ast.Link, *ast.AutoLink:
+			found = true
+			return ast.WalkStop, nil
+		}
+		return ast.WalkContinue, nil
+	})
+	return found
+}
+
+// writeInline writes inline content with pdf.Write, turning links to heading
+// anchors into internal links and other links into URI links
+func (r *pdfRenderer) writeInline(node ast.Node, lineHeight float64) {
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch n := child.(type) {
+		case *ast.Text:
+			r.pdf.Write(lineHeight, stripEmojis(string(n.Segment.Value(r.source))))
+			if n.HardLineBreak() || n.SoftLineBreak() {
+				r.pdf.Ln(lineHeight)
+			}
+
+		case *ast.String:
+			r.pdf.Write(lineHeight, stripEmojis(string(n.Value)))
+
+		case *ast.Link:
+			text := r.extractText(n)
+			dest := string(n.Destination)
+			r.pdf.SetTextColor(0, 102, 204)
+			if link, ok := r.anchors[strings.TrimPrefix(dest, "#")]; ok && strings.HasPrefix(dest, "#") {
+				r.pdf.WriteLinkID(lineHeight, text, link)
+			} else if strings.HasPrefix(dest, "#") {
+				r.pdf.Write(lineHeight, text)
+			} else {
+				r.pdf.WriteLinkString(lineHeight, text, dest)
+			}
+			r.pdf.SetTextColor(0, 0, 0)
+
+		case *ast.AutoLink:
+			url := string(n.URL(r.source))
+			r.pdf.SetTextColor(0, 102, 204)
+			r.pdf.WriteLinkString(lineHeight, url, url)
+			r.pdf.SetTextColor(0, 0, 0)
+
+		case *ast.Emphasis:
+			style := r.pdf.GetFontStyle()
+			if n.Level == 2 {
+				r.pdf.SetFontStyle("B")
+			}
+			r.writeInline(n, lineHeight)
+			r.pdf.SetFontStyle(style)
+
+		case *ast.CodeSpan:
+			family, style := r.pdf.GetFontFamily(), r.pdf.GetFontStyle()
+			size, _ := r.pdf.GetFontSize()
+			r.pdf.SetFont("DejaVuMono", "", size)
+			r.pdf.Write(lineHeight, r.extractText(n))
+			r.pdf.SetFont(family, style, size)
+
+		case *ast.RawHTML:
+			// Comments and tags are not rendered, index terms are recorded
+			// at the current position
+			for _, value := range indexDirectives(n, r.source) {
+				r.addIndexTerms(value)
+			}
+
+		default:
+			r.writeInline(child, lineHeight)
+		}
+	}
+}
+
 // renderCodeBlock renders a fenced code block with monospace font
 func (r *pdfRenderer) renderCodeBlock(node ast.Node) {
 	// Save current position
@@ -213,11 +511,20 @@ func (r *pdfRenderer) renderCodeBlock(node ast.Node) {
 	}
 	code := strings.TrimRight(codeBuilder.String(), "\n")
 
-	// Calculate height needed
+	// Shrink the font when the longest line is wider than the column
 	codeLines := strings.Split(code, "\n")
-	lineHeight := 5.0
+	fontSize := r.fitFontSize("DejaVuMono", "", 10, codeLines, contentWidth-6)
+	r.pdf.SetFont("DejaVuMono", "", fontSize)
+
+	// Calculate height needed
+	lineHeight := fontSize * 0.5
 	blockHeight := float64(len(codeLines))*lineHeight + 6 // padding
 
+	// Keep the block in one column when it fits
+	if r.ensureSpace(blockHeight) {
+		x, y = r.pdf.GetXY()
+	}
+
 	// Draw background
 	r.pdf.SetFillColor(245, 245, 245) // Light gray background
 	r.pdf.Rect(x, y, contentWidth, blockHeight, "F")
@@ -226,8 +533,6 @@ func (r *pdfRenderer) renderCodeBlock(node ast.Node) {
 	r.pdf.SetDrawColor(200, 200, 200)
 	r.pdf.Rect(x, y, contentWidth, blockHeight, "D")
 
-	// Set monospace font (DejaVuMono supports Unicode)
-	r.pdf.SetFont("DejaVuMono", "", 10)
 	r.pdf.SetXY(x+3, y+3)
 
 	// Render each line
@@ -243,6 +548,60 @@ func (r *pdfRenderer) renderCodeBlock(node ast.Node) {
 	r.pdf.SetDrawColor(0, 0, 0)
 }
 
+// renderHTMLBlock applies section directives; other raw HTML is not rendered
+func (r *pdfRenderer) renderHTMLBlock(node *ast.HTMLBlock) {
+	name, value, ok := r.directive(node)
+	if !ok {
+		return
+	}
+
+	switch name {
+	case "columns":
+		if n, err := strconv.Atoi(value); err == nil && n >= 1 && n <= maxColumns {
+			r.startRegion(n)
+		}
+	case "index":
+		r.addIndexTerms(value)
+	}
+}
+
+// directive parses an HTML comment of the form <!-- name: value -->
+func (r *pdfRenderer) directive(node *ast.HTMLBlock) (name, value string, ok bool) {
+	return parseDirective(htmlBlockText(node, r.source))
+}
+
+// htmlBlockText returns the raw source of an HTML block
+func htmlBlockText(node *ast.HTMLBlock, source []byte) string {
+	var buf bytes.Buffer
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		buf.Write(line.Value(source))
+	}
+	if node.HasClosure() {
+		buf.Write(node.ClosureLine.Value(source))
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// parseDirective splits a directive comment into its lower-cased name and value
+func parseDirective(html string) (name, value string, ok bool) {
+	m := directiveRegex.FindStringSubmatch(html)
+	if m == nil {
+		return "", "", false
+	}
+	return strings.ToLower(m[1]), m[2], true
+}
+
+// htmlComment returns the trimmed text of an HTML comment
+func htmlComment(html string) (string, bool) {
+	m := commentRegex.FindStringSubmatch(html)
+	if m == nil {
+		return "", false
+	}
+	return strings.TrimSpace(m[1]), true
+}
+
 // renderThematicBreak renders a horizontal rule
 func (r *pdfRenderer) renderThematicBreak() {
 	r.pdf.Ln(3)
@@ -260,6 +619,7 @@ func (r *pdfRenderer) renderList(node *ast.List, indent int) {
 	itemNum := 1
 	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
 		if listItem, ok := child.(*ast.ListItem); ok {
+			r.markIndexTerms(listItem, 6)
 			indentStr := strings.Repeat("   ", indent)
 			var bullet string
 			if node.IsOrdered() {
@@ -297,6 +657,7 @@ func (r *pdfRenderer) renderList(node *ast.List, indent int) {
 
 // renderBlockquote renders a blockquote with left border
 func (r *pdfRenderer) renderBlockquote(node *ast.Blockquote) {
+	r.markIndexTerms(node, 6)
 	x, y := r.pdf.GetXY()
 
 	// Draw left border
@@ -364,9 +725,16 @@ func (r *pdfRenderer) renderTable(node *extast.Table) {
 	tableWidth := pageWidth - marginLeft - marginRight
 	colWidth := tableWidth / float64(columnCount)
 
+	// Shrink the font when cells are wider than the columns allow
+	var cellTexts []string
+	for _, row := range rows {
+		cellTexts = append(cellTexts, row...)
+	}
+	fontSize := r.fitFontSize("DejaVu", "B", 10, cellTexts, colWidth-2*r.pdf.GetCellMargin())
+
 	// Render table
-	r.pdf.SetFont("DejaVu", "", 10)
-	cellHeight := 7.0
+	cellHeight := fontSize * 0.7
+	r.markIndexTerms(node, cellHeight)
 
 	for rowIdx, row := range rows {
 		// Pad row to match column count
@@ -376,10 +744,10 @@ func (r *pdfRenderer) renderTable(node *extast.Table) {
 
 		// Header row styling
 		if rowIdx == 0 {
-			r.pdf.SetFont("DejaVu", "B", 10)
+			r.pdf.SetFont("DejaVu", "B", fontSize)
 			r.pdf.SetFillColor(230, 230, 230)
 		} else {
-			r.pdf.SetFont("DejaVu", "", 10)
+			r.pdf.SetFont("DejaVu", "", fontSize)
 			r.pdf.SetFillColor(255, 255, 255)
 		}
 
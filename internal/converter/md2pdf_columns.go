@@ -0,0 +1,102 @@
+package converter
+
+import "math"
+
+const (
+	// maxColumns caps the column count so columns stay readable on A4
+	maxColumns = 6
+
+	// columnGutter is the space between two columns in mm
+	columnGutter = 6.0
+
+	// minFontSize is the smallest size code and tables are shrunk to when fitting a column
+	minFontSize = 6.0
+)
+
+// setupColumns records the page margins and installs the page break handler
+// that flows text from one column into the next before adding a page
+func (r *pdfRenderer) setupColumns(columns int) {
+	r.pageLeft, _, r.pageRight, _ = r.pdf.GetMargins()
+	r.pdf.SetAcceptPageBreakFunc(r.acceptPageBreak)
+	r.startRegion(columns)
+}
+
+// startRegion closes the current column region and starts a new one with the
+// given number of columns below the content laid out so far
+func (r *pdfRenderer) startRegion(columns int) {
+	if r.column > 0 {
+		// Earlier columns run to the bottom of the page, so nothing fits below them
+		r.column = 0
+		r.pdf.AddPage()
+	}
+	r.columns = columns
+	r.regionTop = r.pdf.GetY()
+	r.applyColumn()
+}
+
+// columnWidth returns the width of a single column in the current region
+func (r *pdfRenderer) columnWidth() float64 {
+	pageWidth, _ := r.pdf.GetPageSize()
+	usable := pageWidth - r.pageLeft - r.pageRight
+	return (usable - float64(r.columns-1)*columnGutter) / float64(r.columns)
+}
+
+// applyColumn narrows the page margins to the current column
+func (r *pdfRenderer) applyColumn() {
+	pageWidth, _ := r.pdf.GetPageSize()
+	width := r.columnWidth()
+	left := r.pageLeft + float64(r.column)*(width+columnGutter)
+	r.pdf.SetLeftMargin(left)
+	r.pdf.SetRightMargin(pageWidth - left - width)
+	r.pdf.SetX(left)
+}
+
+// acceptPageBreak moves to the next column while one is left on the page,
+// otherwise it lets fpdf add a page and continues in the first column
+func (r *pdfRenderer) acceptPageBreak() bool {
+	if r.column < r.columns-1 {
+		r.column++
+		r.applyColumn()
+		r.pdf.SetY(r.regionTop)
+		return false
+	}
+
+	_, top, _, _ := r.pdf.GetMargins()
+	r.column = 0
+	r.regionTop = top
+	if r.columns > 1 {
+		r.applyColumn()
+	}
+	return true
+}
+
+// ensureSpace moves to the next column or page when a block of height h does
+// not fit below the current position but would fit in an empty one.
+// It reports whether the position changed.
+func (r *pdfRenderer) ensureSpace(h float64) bool {
+	_, pageHeight := r.pdf.GetPageSize()
+	_, top, _, bottom := r.pdf.GetMargins()
+	limit := pageHeight - bottom
+	if r.pdf.GetY()+h <= limit || h > limit-top {
+		return false
+	}
+
+	if r.acceptPageBreak() {
+		r.pdf.AddPage()
+	}
+	return true
+}
+
+// fitFontSize returns the largest font size up to size at which every line
+// fits into width. It leaves the font set to family and style.
+func (r *pdfRenderer) fitFontSize(family, style string, size float64, lines []string, width float64) float64 {
+	r.pdf.SetFont(family, style, size)
+	widest := 0.0
+	for _, line := range lines {
+		widest = max(widest, r.pdf.GetStringWidth(line))
+	}
+	if widest <= width {
+		return size
+	}
+	return math.Max(minFontSize, size*width/widest)
+}
@@ -0,0 +1,244 @@
+package converter
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+)
+
+// definitionIndent is the indentation of definitions below their term in mm
+const definitionIndent = 8.0
+
+// pageRef is a clickable reference to a position in the document
+type pageRef struct {
+	page int
+	link int
+}
+
+// glossaryEntry is a definition list item collected for the glossary
+type glossaryEntry struct {
+	term       string
+	definition string
+	ref        pageRef
+}
+
+// indexTerm is an index entry with the pages it is referenced from
+type indexTerm struct {
+	name string // spelling of the first occurrence
+	refs []pageRef
+}
+
+// bookIndex collects index terms and glossary entries during layout
+type bookIndex struct {
+	terms    map[string]*indexTerm // keyed by lower-cased term
+	glossary []glossaryEntry
+}
+
+func newBookIndex() *bookIndex {
+	return &bookIndex{terms: make(map[string]*indexTerm)}
+}
+
+// mark returns a link to the current position in the document
+func (r *pdfRenderer) mark() pageRef {
+	link := r.pdf.AddLink()
+	r.pdf.SetLink(link, r.pdf.GetY(), r.pdf.PageNo())
+	return pageRef{page: r.pdf.PageNo(), link: link}
+}
+
+// addIndexTerms records index terms separated by semicolons at the current position
+func (r *pdfRenderer) addIndexTerms(value string) {
+	if r.index == nil {
+		return
+	}
+	for _, term := range strings.Split(value, ";") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		key := strings.ToLower(term)
+		entry, ok := r.index.terms[key]
+		if !ok {
+			entry = &indexTerm{name: term}
+			r.index.terms[key] = entry
+		}
+		// One reference per page is enough
+		if n := len(entry.refs); n > 0 && entry.refs[n-1].page == r.pdf.PageNo() {
+			continue
+		}
+		entry.refs = append(entry.refs, r.mark())
+	}
+}
+
+// markIndexTerms records the <!-- index: term --> comments embedded in the
+// inline content of a block at the position of its first line, which is
+// lineHeight high
+func (r *pdfRenderer) markIndexTerms(node ast.Node, lineHeight float64) {
+	for _, value := range indexDirectives(node, r.source) {
+		r.ensureSpace(lineHeight)
+		r.addIndexTerms(value)
+	}
+}
+
+// indexDirectives returns the values of the <!-- index: term --> comments in the inline content of a node
+func indexDirectives(node ast.Node, source []byte) []string {
+	var values []string
+	for _, html := range inlineHTML(node, source) {
+		if name, value, ok := parseDirective(html); ok && name == "index" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// inlineHTML returns the raw inline HTML fragments contained in a block
+func inlineHTML(node ast.Node, source []byte) []string {
+	var fragments []string
+	_ = ast.Walk(node, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		raw, ok := n.(*ast.RawHTML)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		var buf bytes.Buffer
+		for i := 0; i < raw.Segments.Len(); i++ {
+			segment := raw.Segments.At(i)
+			buf.Write(segment.Value(source))
+		}
+		fragments = append(fragments, buf.String())
+		return ast.WalkSkipChildren, nil
+	})
+	return fragments
+}
+
+// renderDefinitionList renders bold terms with indented definitions and
+// collects the terms for the glossary
+func (r *pdfRenderer) renderDefinitionList(node *extast.DefinitionList) {
+	var entry *glossaryEntry
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		switch item := child.(type) {
+		case *extast.DefinitionTerm:
+			r.markIndexTerms(item, 6)
+			if r.index != nil {
+				r.ensureSpace(6)
+				r.index.glossary = append(r.index.glossary, glossaryEntry{
+					term: strings.TrimSpace(r.extractText(item)),
+					ref:  r.mark(),
+				})
+				entry = &r.index.glossary[len(r.index.glossary)-1]
+				r.addIndexTerms(entry.term)
+			}
+			r.pdf.SetFont("DejaVu", "B", 12)
+			r.pdf.MultiCell(0, 6, strings.TrimSpace(r.extractText(item)), "", "", false)
+			r.pdf.SetFont("DejaVu", "", 12)
+
+		case *extast.DefinitionDescription:
+			r.markIndexTerms(item, 6)
+			text := r.extractFormattedText(item)
+			if entry != nil && entry.definition == "" {
+				entry.definition = text
+			}
+			left, _, _, _ := r.pdf.GetMargins()
+			r.pdf.SetX(left + definitionIndent)
+			r.pdf.MultiCell(0, 6, text, "", "", false)
+			r.pdf.Ln(1)
+		}
+	}
+	r.pdf.Ln(2)
+}
+
+// startBackMatter starts a back-of-book section on a new page with a full-width title
+func (r *pdfRenderer) startBackMatter(title string) {
+	r.column = 0
+	r.pdf.AddPage()
+	r.startRegion(1)
+
+	r.pdf.SetFont("DejaVu", "B", 20)
+	r.pdf.MultiCell(0, 10, title, "", "", false)
+	r.pdf.Ln(3)
+	r.pdf.SetFont("DejaVu", "", 12)
+}
+
+// writePageRefs writes comma separated, clickable page numbers
+func (r *pdfRenderer) writePageRefs(refs []pageRef, lineHeight float64) {
+	for i, ref := range refs {
+		if i > 0 {
+			r.pdf.Write(lineHeight, ", ")
+		}
+		r.pdf.WriteLinkID(lineHeight, fmt.Sprintf("%d", ref.page), ref.link)
+	}
+}
+
+// renderGlossary emits the collected definition list entries in alphabetical order
+func (r *pdfRenderer) renderGlossary() {
+	if len(r.index.glossary) == 0 {
+		return
+	}
+
+	entries := append([]glossaryEntry(nil), r.index.glossary...)
+	sort.SliceStable(entries, func(i, j int) bool {
+		return strings.ToLower(entries[i].term) < strings.ToLower(entries[j].term)
+	})
+
+	r.startBackMatter("Glossary")
+	for _, entry := range entries {
+		r.pdf.SetFont("DejaVu", "B", 12)
+		r.pdf.Write(6, entry.term)
+		r.pdf.SetFont("DejaVu", "", 10)
+		r.pdf.Write(6, "  (p. ")
+		r.writePageRefs([]pageRef{entry.ref}, 6)
+		r.pdf.Write(6, ")")
+		r.pdf.Ln(6)
+
+		r.pdf.SetFont("DejaVu", "", 12)
+		r.pdf.MultiCell(0, 6, entry.definition, "", "", false)
+		r.pdf.Ln(3)
+	}
+}
+
+// renderIndex emits the collected index terms alphabetically in two columns, grouped by initial
+func (r *pdfRenderer) renderIndex() {
+	if len(r.index.terms) == 0 {
+		return
+	}
+
+	keys := make([]string, 0, len(r.index.terms))
+	for key := range r.index.terms {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	r.startBackMatter("Index")
+	r.startRegion(2)
+
+	group := ""
+	for _, key := range keys {
+		term := r.index.terms[key]
+		if initial := indexGroup(term.name); initial != group {
+			group = initial
+			r.pdf.Ln(2)
+			r.pdf.SetFont("DejaVu", "B", 12)
+			r.pdf.MultiCell(0, 6, group, "", "", false)
+		}
+
+		r.pdf.SetFont("DejaVu", "", 10)
+		r.pdf.Write(5, term.name+"  ")
+		r.writePageRefs(term.refs, 5)
+		r.pdf.Ln(5)
+	}
+	r.pdf.SetFont("DejaVu", "", 12)
+}
+
+// indexGroup returns the index section a term is listed under
+func indexGroup(term string) string {
+	for _, c := range term {
+		if unicode.IsLetter(c) {
+			return string(unicode.ToUpper(c))
+		}
+		break
+	}
+	return "#"
+}
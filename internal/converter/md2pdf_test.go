@@ -7,7 +7,9 @@ import (
 	"strings"
 	"testing"
 
+	"codeberg.org/go-pdf/fpdf"
 	"github.com/green-creeper/mdtool/pkg/models"
+	"github.com/ledongthuc/pdf"
 )
 
 // errorReader is an io.Reader that always returns an error
@@ -74,3 +76,503 @@ func TestMD2PDFConverter_Convert_ReadError(t *testing.T) {
 		t.Errorf("Expected error message %q, got %q", expectedErrMsg, resp.Error)
 	}
 }
+
+func TestMD2PDFConverter_Columns(t *testing.T) {
+	c := NewMD2PDFConverter()
+
+	var markdown strings.Builder
+	markdown.WriteString("# Newsletter\n\n")
+	for i := 0; i < 60; i++ {
+		markdown.WriteString("Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor.\n\n")
+	}
+
+	var output bytes.Buffer
+	req := &models.ConvertRequest{
+		Input:  strings.NewReader(markdown.String()),
+		Output: &output,
+		Options: map[string]interface{}{
+			"columns":       2,
+			"span_headings": true,
+		},
+	}
+
+	resp := c.Convert(req)
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+
+	reader, err := pdf.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	if err != nil {
+		t.Fatalf("failed to read generated PDF: %v", err)
+	}
+
+	// Text must continue in the right-hand column before the first page ends
+	var rightColumn bool
+	for _, glyph := range reader.Page(1).Content().Text {
+		if glyph.X > 297.6 {
+			rightColumn = true
+			break
+		}
+	}
+	if !rightColumn {
+		t.Error("Expected text in the second column of page 1")
+	}
+}
+
+// findText returns the position of the first glyph of text on a page
+func findText(page pdf.Page, text string) (x, y float64, ok bool) {
+	glyphs := page.Content().Text
+	var b strings.Builder
+	starts := make([]int, 0, len(glyphs))
+	for i, g := range glyphs {
+		for range g.S {
+			starts = append(starts, i)
+		}
+		b.WriteString(g.S)
+	}
+	at := strings.Index(b.String(), text)
+	if at < 0 {
+		return 0, 0, false
+	}
+	g := glyphs[starts[len([]rune(b.String()[:at]))]]
+	return g.X, g.Y, true
+}
+
+func TestMD2PDFConverter_SpanHeadings(t *testing.T) {
+	var markdown strings.Builder
+	markdown.WriteString("Short introduction.\n\n## Spanning Heading\n\n")
+	for i := 0; i < 20; i++ {
+		markdown.WriteString("Lorem ipsum dolor sit amet, consectetur adipiscing elit, sed do eiusmod tempor.\n\n")
+	}
+	markdown.WriteString("## Late Heading\n\nClosing words.\n")
+
+	var output bytes.Buffer
+	resp := NewMD2PDFConverter().Convert(&models.ConvertRequest{
+		Input:   strings.NewReader(markdown.String()),
+		Output:  &output,
+		Options: map[string]interface{}{"columns": 2, "span_headings": true},
+	})
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+	reader, err := pdf.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	if err != nil {
+		t.Fatalf("failed to read generated PDF: %v", err)
+	}
+	page := reader.Page(1)
+
+	// The heading follows the introduction in the first column's place and
+	// the text after it starts a new pair of columns below the heading
+	_, introY, ok := findText(page, "Short introduction.")
+	if !ok {
+		t.Fatal("Expected the introduction on page 1")
+	}
+	headingX, headingY, ok := findText(page, "Spanning Heading")
+	if !ok || headingX > 100 || headingY >= introY {
+		t.Fatalf("Expected the heading below the introduction at the left margin, got (%.0f, %.0f) ok=%v", headingX, headingY, ok)
+	}
+	right := false
+	for _, g := range page.Content().Text {
+		if g.X > 297.6 {
+			right = true
+			if g.Y >= headingY {
+				t.Errorf("Expected the second column to start below the heading, got text at y %.0f", g.Y)
+				break
+			}
+		}
+	}
+	if !right {
+		t.Error("Expected the section to flow into the second column of page 1")
+	}
+
+	// A heading reached in the second column stays there instead of
+	// starting a new page
+	if x, _, ok := findText(page, "Late Heading"); !ok || x < 297.6 {
+		t.Errorf("Expected the late heading in the second column of page 1, got x %.0f ok=%v", x, ok)
+	}
+}
+
+func TestMD2PDFConverter_ColumnsDirective(t *testing.T) {
+	var markdown strings.Builder
+	markdown.WriteString("# Title Across The Page\n\n<!-- columns: 3 -->\n\n")
+	for i := 0; i < 80; i++ {
+		markdown.WriteString("Three column text.\n\n")
+	}
+
+	var output bytes.Buffer
+	resp := NewMD2PDFConverter().Convert(&models.ConvertRequest{
+		Input:  strings.NewReader(markdown.String()),
+		Output: &output,
+	})
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+	reader, err := pdf.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	if err != nil {
+		t.Fatalf("failed to read generated PDF: %v", err)
+	}
+	page := reader.Page(1)
+
+	_, titleY, ok := findText(page, "Title Across The Page")
+	if !ok {
+		t.Fatal("Expected the title on page 1")
+	}
+	third := false
+	for _, g := range page.Content().Text {
+		if g.X > 2*595.28/3 {
+			third = true
+			if g.Y >= titleY {
+				t.Errorf("Expected the columns to start below the title, got text at y %.0f", g.Y)
+				break
+			}
+		}
+	}
+	if !third {
+		t.Error("Expected text in the third column of page 1 after the directive")
+	}
+}
+
+func TestMD2PDFConverter_InvalidColumns(t *testing.T) {
+	c := NewMD2PDFConverter()
+
+	var output bytes.Buffer
+	req := &models.ConvertRequest{
+		Input:   strings.NewReader("# Title\n"),
+		Output:  &output,
+		Options: map[string]interface{}{"columns": 0},
+	}
+
+	resp := c.Convert(req)
+	if resp.Success {
+		t.Fatal("Convert() should have failed")
+	}
+	if !strings.Contains(resp.Error.Error(), "invalid column count") {
+		t.Errorf("Expected invalid column count error, got %v", resp.Error)
+	}
+}
+
+func TestMD2PDFConverter_IndexAndGlossary(t *testing.T) {
+	c := NewMD2PDFConverter()
+
+	markdown := `# Manual
+
+<!-- index: Deployment -->
+Deploying the service <!-- index: rollback; Canary --> is covered here.
+
+Rollback
+: Returning to the previous release.
+`
+
+	var output bytes.Buffer
+	req := &models.ConvertRequest{
+		Input:  strings.NewReader(markdown),
+		Output: &output,
+		Options: map[string]interface{}{
+			"index":    true,
+			"glossary": true,
+		},
+	}
+
+	resp := c.Convert(req)
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+
+	// "Rollback" from the glossary merges with the "rollback" marker
+	if resp.Metadata["index_terms"] != "3" {
+		t.Errorf("Expected 3 index terms, got %s", resp.Metadata["index_terms"])
+	}
+	if resp.Metadata["glossary_terms"] != "1" {
+		t.Errorf("Expected 1 glossary term, got %s", resp.Metadata["glossary_terms"])
+	}
+
+	reader, err := pdf.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	if err != nil {
+		t.Fatalf("failed to read generated PDF: %v", err)
+	}
+	if reader.NumPage() != 3 {
+		t.Errorf("Expected content, glossary and index pages, got %d pages", reader.NumPage())
+	}
+}
+
+func TestMD2PDFConverter_IndexPageBreak(t *testing.T) {
+	// A paragraph long enough to continue on page 2, with one index marker
+	// at its start and one at its end
+	markdown := "First <!-- index: Alpha --> " + strings.Repeat("Lorem ipsum dolor sit amet, consectetur adipiscing elit. ", 120) +
+		"<!-- index: Omega -->\n"
+
+	var output bytes.Buffer
+	resp := NewMD2PDFConverter().Convert(&models.ConvertRequest{
+		Input:   strings.NewReader(markdown),
+		Output:  &output,
+		Options: map[string]interface{}{"index": true},
+	})
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+
+	reader, err := pdf.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	if err != nil {
+		t.Fatalf("failed to read generated PDF: %v", err)
+	}
+	if reader.NumPage() != 3 {
+		t.Fatalf("Expected two content pages and the index, got %d pages", reader.NumPage())
+	}
+	index := reader.Page(3)
+	for _, entry := range []string{"Alpha  1", "Omega  2"} {
+		if _, _, ok := findText(index, entry); !ok {
+			t.Errorf("Expected index entry %q", entry)
+		}
+	}
+}
+
+func TestMD2PDFConverter_HeadingAttributes(t *testing.T) {
+	c := NewMD2PDFConverter()
+
+	markdown := `# Guide {#top .center}
+
+Jump to [the setup](#setup).
+
+## Setup {#setup .accent}
+
+API
+: Application programming interface.
+`
+
+	var output bytes.Buffer
+	req := &models.ConvertRequest{
+		Input:  strings.NewReader(markdown),
+		Output: &output,
+	}
+
+	resp := c.Convert(req)
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+
+	// The link to #setup becomes an internal link annotation
+	if !bytes.Contains(output.Bytes(), []byte("/Subtype /Link")) || !bytes.Contains(output.Bytes(), []byte("/Dest")) {
+		t.Error("Expected an internal link to the heading anchor")
+	}
+
+	reader, err := pdf.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	if err != nil {
+		t.Fatalf("failed to read generated PDF: %v", err)
+	}
+	text, err := reader.Page(1).GetPlainText(nil)
+	if err != nil {
+		t.Fatalf("failed to extract text: %v", err)
+	}
+	if strings.Contains(text, "{#") || strings.Contains(text, ".accent") {
+		t.Errorf("Heading attributes leaked into the text: %q", text)
+	}
+	if strings.Contains(text, ": Application") {
+		t.Errorf("Definition list rendered as a plain paragraph: %q", text)
+	}
+}
+
+func TestMD2PDFConverter_UnknownTheme(t *testing.T) {
+	c := NewMD2PDFConverter()
+
+	var output bytes.Buffer
+	req := &models.ConvertRequest{
+		Input:   strings.NewReader("# Title\n"),
+		Output:  &output,
+		Options: map[string]interface{}{"theme": "neon"},
+	}
+
+	resp := c.Convert(req)
+	if resp.Success {
+		t.Fatal("Convert() should have failed")
+	}
+	if !strings.Contains(resp.Error.Error(), `unknown theme "neon"`) {
+		t.Errorf("Expected unknown theme error, got %v", resp.Error)
+	}
+}
+
+func TestMD2PDFConverter_PDFA(t *testing.T) {
+	c := NewMD2PDFConverter()
+
+	markdown := `---
+title: Quarterly Report
+author: "Jane Doe"
+tags: [finance, q3]
+---
+
+# Report
+
+See [the website](https://example.com).
+`
+
+	var output bytes.Buffer
+	req := &models.ConvertRequest{
+		Input:   strings.NewReader(markdown),
+		Output:  &output,
+		Options: map[string]interface{}{"pdfa": true},
+	}
+
+	resp := c.Convert(req)
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+	if resp.Metadata["pdfa"] != "2b" {
+		t.Errorf("Expected pdfa metadata 2b, got %q", resp.Metadata["pdfa"])
+	}
+
+	for _, want := range []string{
+		"/OutputIntents",
+		"/GTS_PDFA1",
+		"<pdfaid:part>2</pdfaid:part>",
+		"<pdfaid:conformance>B</pdfaid:conformance>",
+		">Quarterly Report</rdf:li>",
+		"<pdf:Keywords>finance, q3</pdf:Keywords>",
+		"/Type /Annot /F 4",
+		"/ID [<",
+	} {
+		if !bytes.Contains(output.Bytes(), []byte(want)) {
+			t.Errorf("Expected output to contain %q", want)
+		}
+	}
+	if err := validatePDFA(output.Bytes()); err != nil {
+		t.Errorf("validatePDFA() = %v", err)
+	}
+
+	// The fonts are embedded in full instead of as subsets
+	file, err := parsePDFFile(output.Bytes())
+	if err != nil {
+		t.Fatalf("parsePDFFile() error = %v", err)
+	}
+	fonts := type0Fonts(file)
+	if len(fonts) == 0 {
+		t.Fatal("Expected fonts in the output")
+	}
+	for _, font := range fonts {
+		fontFile, _ := file.object(font.fontFile)
+		want := fmt.Sprintf("/Length1 %d ", len(fontProgram(font.name)))
+		if subsetTagRegex.MatchString(font.name) || !bytes.Contains(fontFile.dictText(), []byte(want)) {
+			t.Errorf("Expected font %s to be embedded in full", font.name)
+		}
+	}
+
+	// The rewritten file must still be a readable PDF without the front matter
+	reader, err := pdf.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	if err != nil {
+		t.Fatalf("failed to read generated PDF: %v", err)
+	}
+	text, err := reader.Page(1).GetPlainText(nil)
+	if err != nil {
+		t.Fatalf("failed to extract text: %v", err)
+	}
+	if strings.Contains(text, "author") || !strings.Contains(text, "Report") {
+		t.Errorf("Unexpected page text: %q", text)
+	}
+}
+
+func TestMD2PDFConverter_FrontMatterWithoutPDFA(t *testing.T) {
+	// Without --pdfa a leading --- block is ordinary Markdown
+	var output bytes.Buffer
+	resp := NewMD2PDFConverter().Convert(&models.ConvertRequest{
+		Input:  strings.NewReader("---\nauthor: Jane Doe\n---\n\nBody text.\n"),
+		Output: &output,
+	})
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+
+	reader, err := pdf.NewReader(bytes.NewReader(output.Bytes()), int64(output.Len()))
+	if err != nil {
+		t.Fatalf("failed to read generated PDF: %v", err)
+	}
+	text, err := reader.Page(1).GetPlainText(nil)
+	if err != nil {
+		t.Fatalf("failed to extract text: %v", err)
+	}
+	if !strings.Contains(text, "author: Jane Doe") {
+		t.Errorf("Expected the front matter in the page text, got %q", text)
+	}
+}
+
+func TestValidatePDFA(t *testing.T) {
+	// fpdf output without the PDF/A rewrite and with transparency must be rejected
+	doc := fpdf.New("P", "mm", "A4", "")
+	addFonts(doc)
+	doc.AddPage()
+	doc.SetAlpha(0.5, "Normal")
+	doc.SetFont("DejaVu", "", 12)
+	doc.Cell(40, 10, "Faded")
+
+	var buf bytes.Buffer
+	if err := doc.Output(&buf); err != nil {
+		t.Fatalf("failed to generate PDF: %v", err)
+	}
+
+	err := validatePDFA(buf.Bytes())
+	if err == nil {
+		t.Fatal("validatePDFA() should have failed")
+	}
+	for _, want := range []string{"transparency", "no output intent", "no file identifier", "not embedded in full"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %q in %v", want, err)
+		}
+	}
+}
+
+func TestFontGlyphs(t *testing.T) {
+	glyphs, err := fontGlyphs(dejaVuSansFont)
+	if err != nil {
+		t.Fatalf("fontGlyphs() error = %v", err)
+	}
+	for code, want := range map[rune]uint16{' ': 3, 'A': 36, '€': 2948} {
+		if got := glyphs[code]; got != want {
+			t.Errorf("glyph of %q = %d, want %d", code, got, want)
+		}
+	}
+}
+
+func TestSplitFrontMatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		wantMeta map[string]string
+		wantBody string
+	}{
+		{
+			name:     "Flat fields and lists",
+			input:    "---\ntitle: 'Notes'\nkeywords:\n  - a\n  - b\n---\n# Body\n",
+			wantMeta: map[string]string{"title": "Notes", "keywords": "a, b"},
+			wantBody: "# Body\n",
+		},
+		{
+			name:     "No front matter",
+			input:    "# Body\n",
+			wantBody: "# Body\n",
+		},
+		{
+			name:     "Unterminated block is Markdown",
+			input:    "---\ntitle: Notes\n",
+			wantBody: "---\ntitle: Notes\n",
+		},
+		{
+			name:     "Heading between rules is Markdown",
+			input:    "---\n# Intro\n---\n# Next\n",
+			wantBody: "---\n# Intro\n---\n# Next\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			meta, body := splitFrontMatter([]byte(tt.input))
+			if string(body) != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+			if len(meta) != len(tt.wantMeta) {
+				t.Errorf("meta = %v, want %v", meta, tt.wantMeta)
+			}
+			for k, v := range tt.wantMeta {
+				if meta[k] != v {
+					t.Errorf("meta[%q] = %q, want %q", k, meta[k], v)
+				}
+			}
+		})
+	}
+}
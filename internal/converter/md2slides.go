@@ -0,0 +1,437 @@
+package converter
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"codeberg.org/go-pdf/fpdf"
+	"github.com/green-creeper/mdtool/pkg/models"
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+)
+
+const (
+	// Slides are 10in x 5.625in, a 16:9 landscape page
+	slideWidth  = 254.0
+	slideHeight = 142.875
+	slideMargin = 15.0
+)
+
+// MD2SlidesConverter converts Markdown split by thematic breaks into a slide deck PDF
+type MD2SlidesConverter struct{}
+
+// NewMD2SlidesConverter creates a new Markdown to slide deck converter
+func NewMD2SlidesConverter() *MD2SlidesConverter {
+	return &MD2SlidesConverter{}
+}
+
+// slide is one section of the deck between two thematic breaks
+type slide struct {
+	nodes []ast.Node
+	notes []string
+}
+
+// slideRenderer handles the slide deck rendering state
+type slideRenderer struct {
+	*pdfRenderer
+	theme theme
+	build bool
+
+	// Bullet build-up state for the page being rendered
+	visible int  // number of top-level bullets to show, or -1 for all
+	bullets int  // number of top-level bullets rendered so far
+	hidden  bool // set once the first hidden bullet is reached
+}
+
+// Convert converts Markdown to a slide deck PDF and optionally a speaker notes PDF
+func (c *MD2SlidesConverter) Convert(req *models.ConvertRequest) *models.ConvertResponse {
+	// Read Markdown content
+	mdBytes, err := io.ReadAll(req.Input)
+	if err != nil {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   fmt.Errorf("failed to read Markdown input: %w", err),
+		}
+	}
+
+	// Reuse the md2pdf parse and split the document into slides
+	slides, themeName := splitSlides(parseMarkdown(mdBytes), mdBytes)
+
+	// The --theme option wins over a theme directive in the deck
+	themeName = req.StringOption("theme", themeName)
+	if themeName == "" {
+		themeName = "light"
+	}
+	deckTheme, ok := themes[themeName]
+	if !ok {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   fmt.Errorf("unknown theme %q (available: %s)", themeName, strings.Join(themeNames(), ", ")),
+		}
+	}
+
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		OrientationStr: "L",
+		UnitStr:        "mm",
+		Size:           fpdf.SizeType{Wd: slideHeight, Ht: slideWidth},
+	})
+	addFonts(pdf)
+	pdf.SetMargins(slideMargin, slideMargin, slideMargin)
+	pdf.SetAutoPageBreak(false, 0)
+
+	renderer := &slideRenderer{
+		pdfRenderer: &pdfRenderer{pdf: pdf, source: mdBytes},
+		theme:       deckTheme,
+		build:       req.BoolOption("build", false),
+	}
+	for i, s := range slides {
+		renderer.renderSlide(s, i+1)
+	}
+
+	// Write PDF to output
+	err = pdf.Output(req.Output)
+	if err != nil {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   fmt.Errorf("failed to write PDF: %w", err),
+		}
+	}
+
+	// Speaker notes go to a separate document when a writer is provided
+	if notes, ok := req.Options["notes"].(io.Writer); ok && notes != nil {
+		err = renderer.writeNotes(notes, slides)
+		if err != nil {
+			return &models.ConvertResponse{
+				Success: false,
+				Error:   fmt.Errorf("failed to write speaker notes: %w", err),
+			}
+		}
+	}
+
+	return &models.ConvertResponse{
+		Success: true,
+		Metadata: map[string]string{
+			"converter": "md2slides",
+			"slides":    fmt.Sprintf("%d", len(slides)),
+			"pages":     fmt.Sprintf("%d", pdf.PageCount()),
+			"theme":     themeName,
+		},
+	}
+}
+
+// splitSlides splits the document at thematic breaks, collecting HTML comments
+// as speaker notes. It also returns the theme named by a theme directive.
+func splitSlides(doc ast.Node, source []byte) ([]slide, string) {
+	var slides []slide
+	var current slide
+	var themeName string
+
+	flush := func() {
+		if len(current.nodes) > 0 || len(current.notes) > 0 {
+			slides = append(slides, current)
+		}
+		current = slide{}
+	}
+
+	for child := doc.FirstChild(); child != nil; child = child.NextSibling() {
+		switch node := child.(type) {
+		case *ast.ThematicBreak:
+			flush()
+
+		case *ast.HTMLBlock:
+			html := htmlBlockText(node, source)
+			if name, value, ok := parseDirective(html); ok && name == "theme" {
+				themeName = strings.ToLower(value)
+			} else if note, ok := htmlComment(html); ok && note != "" {
+				current.notes = append(current.notes, note)
+			}
+
+		default:
+			current.notes = append(current.notes, inlineComments(node, source)...)
+			current.nodes = append(current.nodes, node)
+		}
+	}
+	flush()
+
+	return slides, themeName
+}
+
+// inlineComments returns the HTML comments embedded in the inline content of a block
+func inlineComments(node ast.Node, source []byte) []string {
+	var notes []string
+	for _, html := range inlineHTML(node, source) {
+		if note, ok := htmlComment(html); ok && note != "" {
+			notes = append(notes, note)
+		}
+	}
+	return notes
+}
+
+// renderSlide renders one slide, or one page per bullet when build-up is enabled
+func (r *slideRenderer) renderSlide(s slide, number int) {
+	steps := 1
+	if r.build {
+		steps = max(1, countBullets(s.nodes))
+	}
+
+	for step := 1; step <= steps; step++ {
+		r.visible = -1
+		if steps > 1 {
+			r.visible = step
+		}
+		r.bullets = 0
+		r.hidden = false
+
+		r.beginSlide(number)
+		if isTitleSlide(s.nodes) {
+			r.renderTitleSlide(s.nodes)
+			continue
+		}
+		for i, node := range s.nodes {
+			if r.hidden {
+				break
+			}
+			if heading, ok := node.(*ast.Heading); ok && i == 0 {
+				r.renderSlideTitle(heading)
+				continue
+			}
+			r.renderSlideNode(node)
+		}
+	}
+}
+
+// beginSlide adds a page with the theme background and the slide number
+func (r *slideRenderer) beginSlide(number int) {
+	r.pdf.AddPage()
+	setFillColor(r.pdf, r.theme.Background)
+	r.pdf.Rect(0, 0, slideWidth, slideHeight, "F")
+
+	r.pdf.SetFont("DejaVu", "", 10)
+	setTextColor(r.pdf, r.theme.Accent)
+	r.pdf.SetXY(slideMargin, slideHeight-slideMargin/2-3)
+	r.pdf.CellFormat(slideWidth-2*slideMargin, 5, fmt.Sprintf("%d", number), "", 0, "R", false, 0, "")
+
+	r.pdf.SetXY(slideMargin, slideMargin)
+}
+
+// isTitleSlide reports whether a slide only holds a top-level title and short text
+func isTitleSlide(nodes []ast.Node) bool {
+	if len(nodes) == 0 {
+		return false
+	}
+	if heading, ok := nodes[0].(*ast.Heading); !ok || heading.Level != 1 {
+		return false
+	}
+	for _, node := range nodes[1:] {
+		switch node.(type) {
+		case *ast.Heading, *ast.Paragraph:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// renderTitleSlide renders a title slide with its text centered on the page
+func (r *slideRenderer) renderTitleSlide(nodes []ast.Node) {
+	r.pdf.SetY(slideHeight * 0.35)
+	for i, node := range nodes {
+		size := r.theme.BodySize
+		style := ""
+		color := r.theme.Text
+		if i == 0 {
+			size = r.theme.TitleSize * 1.25
+			style = "B"
+			color = r.theme.Title
+		}
+		r.pdf.SetFont("DejaVu", style, size)
+		setTextColor(r.pdf, color)
+		r.pdf.MultiCell(0, size*0.5, r.extractFormattedText(node), "", "C", false)
+		r.pdf.Ln(4)
+	}
+}
+
+// renderSlideTitle renders the heading that opens a slide with an accent rule below it
+func (r *slideRenderer) renderSlideTitle(node *ast.Heading) {
+	r.pdf.SetFont("DejaVu", "B", r.theme.TitleSize)
+	setTextColor(r.pdf, r.theme.Title)
+	r.pdf.MultiCell(0, r.theme.TitleSize*0.5, r.extractText(node), "", "L", false)
+
+	setFillColor(r.pdf, r.theme.Accent)
+	r.pdf.Rect(slideMargin, r.pdf.GetY()+1, 30, 1.2, "F")
+	r.pdf.Ln(8)
+}
+
+// renderSlideNode renders a block node of the slide body
+func (r *slideRenderer) renderSlideNode(n ast.Node) {
+	switch node := n.(type) {
+	case *ast.Heading:
+		size := r.theme.BodySize * 1.2
+		r.pdf.SetFont("DejaVu", "B", size)
+		setTextColor(r.pdf, r.theme.Title)
+		r.pdf.MultiCell(0, size*0.5, r.extractText(node), "", "L", false)
+		r.pdf.Ln(3)
+
+	case *ast.Paragraph, *ast.TextBlock:
+		r.pdf.SetFont("DejaVu", "", r.theme.BodySize)
+		setTextColor(r.pdf, r.theme.Text)
+		r.pdf.MultiCell(0, r.theme.BodySize*0.5, r.extractFormattedText(node), "", "L", false)
+		r.pdf.Ln(3)
+
+	case *ast.List:
+		r.renderBullets(node, 0)
+
+	case *ast.FencedCodeBlock, *ast.CodeBlock:
+		r.renderSlideCode(node)
+
+	case *ast.Blockquote:
+		x, y := r.pdf.GetXY()
+		r.pdf.SetFont("DejaVu", "", r.theme.BodySize)
+		setTextColor(r.pdf, r.theme.Text)
+		r.pdf.SetX(x + 6)
+		r.pdf.MultiCell(0, r.theme.BodySize*0.5, r.extractFormattedText(node), "", "L", false)
+		setFillColor(r.pdf, r.theme.Accent)
+		r.pdf.Rect(x, y, 1.5, r.pdf.GetY()-y, "F")
+		r.pdf.Ln(3)
+
+	case *extast.Table:
+		// Table cells have their own light fill, so keep dark text on them
+		r.pdf.SetTextColor(0, 0, 0)
+		r.renderTable(node)
+
+	default:
+		for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+			r.renderSlideNode(child)
+		}
+	}
+}
+
+// renderBullets renders a list, stopping at the first bullet hidden by the build-up
+func (r *slideRenderer) renderBullets(node *ast.List, depth int) {
+	size := r.theme.BodySize - float64(depth)*3
+	lineHeight := size * 0.5
+	indent := slideMargin + float64(depth)*10
+
+	itemNum := 1
+	for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+		item, ok := child.(*ast.ListItem)
+		if !ok {
+			continue
+		}
+		if depth == 0 {
+			if r.visible >= 0 && r.bullets >= r.visible {
+				r.hidden = true
+				return
+			}
+			r.bullets++
+		}
+
+		bullet := "•"
+		if node.IsOrdered() {
+			bullet = fmt.Sprintf("%d.", itemNum)
+			itemNum++
+		}
+
+		r.pdf.SetFont("DejaVu", "B", size)
+		setTextColor(r.pdf, r.theme.Accent)
+		r.pdf.SetX(indent)
+		r.pdf.CellFormat(8, lineHeight, bullet, "", 0, "L", false, 0, "")
+
+		r.pdf.SetFont("DejaVu", "", size)
+		setTextColor(r.pdf, r.theme.Text)
+		for itemChild := item.FirstChild(); itemChild != nil; itemChild = itemChild.NextSibling() {
+			switch block := itemChild.(type) {
+			case *ast.Paragraph, *ast.TextBlock:
+				r.pdf.MultiCell(0, lineHeight, r.extractFormattedText(block), "", "L", false)
+			case *ast.List:
+				r.renderBullets(block, depth+1)
+			}
+		}
+		r.pdf.Ln(2)
+	}
+}
+
+// renderSlideCode renders a code block in the theme colors, scaled to the slide width
+func (r *slideRenderer) renderSlideCode(node ast.Node) {
+	var codeBuilder strings.Builder
+	lines := node.Lines()
+	for i := 0; i < lines.Len(); i++ {
+		line := lines.At(i)
+		codeBuilder.Write(line.Value(r.source))
+	}
+	codeLines := strings.Split(strings.TrimRight(codeBuilder.String(), "\n"), "\n")
+
+	x, y := r.pdf.GetXY()
+	width := slideWidth - 2*slideMargin
+	fontSize := r.fitFontSize("DejaVuMono", "", r.theme.BodySize*0.7, codeLines, width-8)
+	lineHeight := fontSize * 0.5
+	height := float64(len(codeLines))*lineHeight + 8
+
+	setFillColor(r.pdf, r.theme.CodeBackground)
+	r.pdf.Rect(x, y, width, height, "F")
+
+	r.pdf.SetFont("DejaVuMono", "", fontSize)
+	setTextColor(r.pdf, r.theme.CodeText)
+	r.pdf.SetXY(x+4, y+4)
+	for _, line := range codeLines {
+		r.pdf.CellFormat(width-8, lineHeight, line, "", 2, "", false, 0, "")
+		r.pdf.SetX(x + 4)
+	}
+	r.pdf.SetXY(x, y+height+4)
+}
+
+// countBullets returns the number of top-level list items on a slide
+func countBullets(nodes []ast.Node) int {
+	count := 0
+	for _, node := range nodes {
+		if list, ok := node.(*ast.List); ok {
+			count += list.ChildCount()
+		}
+	}
+	return count
+}
+
+// writeNotes renders the speaker notes of every slide into a portrait A4 PDF
+func (r *slideRenderer) writeNotes(w io.Writer, slides []slide) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	addFonts(pdf)
+	pdf.AddPage()
+
+	for i, s := range slides {
+		title := ""
+		if len(s.nodes) > 0 {
+			if heading, ok := s.nodes[0].(*ast.Heading); ok {
+				title = ": " + r.extractText(heading)
+			}
+		}
+
+		pdf.SetFont("DejaVu", "B", 14)
+		pdf.MultiCell(0, 7, fmt.Sprintf("Slide %d%s", i+1, title), "", "", false)
+		pdf.Ln(2)
+
+		pdf.SetFont("DejaVu", "", 12)
+		if len(s.notes) == 0 {
+			pdf.SetTextColor(150, 150, 150)
+			pdf.MultiCell(0, 6, "(no notes)", "", "", false)
+			pdf.SetTextColor(0, 0, 0)
+		}
+		for _, note := range s.notes {
+			pdf.MultiCell(0, 6, stripEmojis(note), "", "", false)
+			pdf.Ln(2)
+		}
+		pdf.Ln(6)
+	}
+
+	return pdf.Output(w)
+}
+
+// Name returns the converter name
+func (c *MD2SlidesConverter) Name() string {
+	return "Markdown to Slides Converter"
+}
+
+// SupportedFormats returns the formats this converter supports
+func (c *MD2SlidesConverter) SupportedFormats() (string, string) {
+	return "markdown", "pdf"
+}
@@ -0,0 +1,97 @@
+package converter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/green-creeper/mdtool/pkg/models"
+)
+
+const testDeck = `<!-- theme: dark -->
+
+# Talk Title
+
+Subtitle
+
+---
+
+## Agenda
+
+- One
+- Two
+- Three
+
+<!-- Remember to smile. -->
+`
+
+func TestMD2SlidesConverter_Convert(t *testing.T) {
+	c := NewMD2SlidesConverter()
+
+	var output, notes bytes.Buffer
+	req := &models.ConvertRequest{
+		Input:  strings.NewReader(testDeck),
+		Output: &output,
+		Options: map[string]interface{}{
+			"build": true,
+			"notes": &notes,
+		},
+	}
+
+	resp := c.Convert(req)
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+
+	if resp.Metadata["slides"] != "2" {
+		t.Errorf("Expected 2 slides, got %s", resp.Metadata["slides"])
+	}
+	// One page for the title slide and one per bullet of the agenda
+	if resp.Metadata["pages"] != "4" {
+		t.Errorf("Expected 4 pages with bullet build-up, got %s", resp.Metadata["pages"])
+	}
+	if resp.Metadata["theme"] != "dark" {
+		t.Errorf("Expected theme from deck directive, got %s", resp.Metadata["theme"])
+	}
+
+	if !bytes.HasPrefix(output.Bytes(), []byte("%PDF-")) {
+		t.Error("Slide output does not appear to be a valid PDF file")
+	}
+	if !bytes.HasPrefix(notes.Bytes(), []byte("%PDF-")) {
+		t.Error("Notes output does not appear to be a valid PDF file")
+	}
+}
+
+func TestMD2SlidesConverter_UnknownTheme(t *testing.T) {
+	c := NewMD2SlidesConverter()
+
+	var output bytes.Buffer
+	req := &models.ConvertRequest{
+		Input:   strings.NewReader(testDeck),
+		Output:  &output,
+		Options: map[string]interface{}{"theme": "neon"},
+	}
+
+	resp := c.Convert(req)
+	if resp.Success {
+		t.Fatal("Convert() should have failed")
+	}
+	if !strings.Contains(resp.Error.Error(), `unknown theme "neon"`) {
+		t.Errorf("Expected unknown theme error, got %v", resp.Error)
+	}
+}
+
+func TestSplitSlides(t *testing.T) {
+	source := []byte(testDeck)
+	slides, theme := splitSlides(parseMarkdown(source), source)
+
+	if theme != "dark" {
+		t.Errorf("Expected theme dark, got %q", theme)
+	}
+	if len(slides) != 2 {
+		t.Fatalf("Expected 2 slides, got %d", len(slides))
+	}
+	if len(slides[1].notes) != 1 || slides[1].notes[0] != "Remember to smile." {
+		t.Errorf("Expected speaker note on second slide, got %q", slides[1].notes)
+	}
+}
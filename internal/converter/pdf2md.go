@@ -4,12 +4,19 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 
 	"github.com/green-creeper/mdtool/pkg/models"
 	"github.com/ledongthuc/pdf"
 )
 
+// maxPageTreeDepth limits the page tree levels searched for inherited page attributes
+const maxPageTreeDepth = 32
+
 // PDF2MDConverter converts PDF to Markdown
 type PDF2MDConverter struct{}
 
@@ -20,8 +27,8 @@ func NewPDF2MDConverter() *PDF2MDConverter {
 
 // Convert extracts text from PDF and converts to Markdown
 func (c *PDF2MDConverter) Convert(req *models.ConvertRequest) *models.ConvertResponse {
-	// Read PDF content into a temporary buffer
-	pdfBytes, err := io.ReadAll(req.Input)
+	// Files are read in place; other input is buffered in memory
+	src, size, err := pdfSource(req.Input)
 	if err != nil {
 		return &models.ConvertResponse{
 			Success: false,
@@ -29,11 +36,50 @@ func (c *PDF2MDConverter) Convert(req *models.ConvertRequest) *models.ConvertRes
 		}
 	}
 
-	// Create a ReaderAt from bytes
-	reader := &bytesReaderAt{data: pdfBytes}
+	tables := req.StringOption("tables", tablesAuto)
+	if tables != tablesOff && tables != tablesAuto && tables != tablesAggressive {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   fmt.Errorf("invalid tables mode %q (must be off, auto or aggressive)", tables),
+		}
+	}
+
+	format := req.StringOption("format", formatMarkdown)
+	if format != formatMarkdown && format != formatJSON {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   fmt.Errorf("invalid format %q (must be markdown or json)", format),
+		}
+	}
+
+	split := req.StringOption("split", "")
+	outputDir := req.StringOption("output_dir", "")
+	if split != "" && split != splitPage && split != splitHeading {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   fmt.Errorf("invalid split mode %q (must be page or heading)", split),
+		}
+	}
+	if split != "" && format == formatJSON {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   errors.New("split output is only available in markdown format"),
+		}
+	}
+	if split != "" && outputDir == "" {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   errors.New("split output requires an output directory"),
+		}
+	}
+
+	jobs := req.IntOption("jobs", 0)
+	if jobs <= 0 {
+		jobs = runtime.NumCPU()
+	}
 
-	// Open PDF
-	pdfReader, err := pdf.NewReader(reader, int64(len(pdfBytes)))
+	// Open PDF, decrypting it if needed
+	pdfReader, err := openPDF(src, size, req.StringOption("password", ""))
 	if err != nil {
 		return &models.ConvertResponse{
 			Success: false,
@@ -41,48 +87,458 @@ func (c *PDF2MDConverter) Convert(req *models.ConvertRequest) *models.ConvertRes
 		}
 	}
 
+	numPages := pdfReader.NumPage()
+	selected, err := parsePageRanges(req.StringOption("pages", ""), numPages)
+	if err != nil {
+		return &models.ConvertResponse{
+			Success: false,
+			Error:   err,
+		}
+	}
+
+	// Images are only extracted when there is a directory to save them to
+	var images *imageExtractor
+	if dir := req.StringOption("assets_dir", ""); dir != "" {
+		images = newImageExtractor(src, size, pdfReader, dir, req.StringOption("assets_prefix", filepath.Base(dir)), req.StringOption("assets_name", ""))
+	}
+
+	// Tagged PDFs are converted from their structure tree, which marks
+	// headings, paragraphs, lists and tables more reliably than the layout
+	// heuristics do
+	tree := readStructTree(pdfReader)
+
+	// Workers stop taking pages once the conversion returns
+	quit := make(chan struct{})
+	defer close(quit)
+
+	// The first pass reads the text of every page in parallel, but keeps
+	// only the statistics the heuristics need from the whole document.
+	// Pages that cannot be read fail strict mode here, before anything is
+	// written; skipped images remain warnings.
+	stats := newDocumentStats()
+	scanned := make([]pageResult, len(selected))
+	scannedStats := make([]pageStats, len(selected))
+	window := make(chan struct{}, jobs)
+	done := runPages(len(selected), jobs, window, quit, func(i int) {
+		scanned[i] = extractPage(pdfReader, selected[i], nil, nil, func(save func()) { save() })
+		if scanned[i].ok {
+			scannedStats[i] = collectPageStats(scanned[i].layout)
+		}
+		scanned[i].layout = pageLayout{}
+	})
+	var failures []models.Warning
+	for i := range selected {
+		<-done[i]
+		if scanned[i].ok {
+			stats.add(scannedStats[i])
+		} else {
+			failures = append(failures, scanned[i].warnings...)
+		}
+		scanned[i], scannedStats[i] = pageResult{}, pageStats{}
+		<-window
+	}
+	if req.BoolOption("strict", false) && len(failures) > 0 {
+		return &models.ConvertResponse{
+			Success:  false,
+			Error:    fmt.Errorf("failed to extract %s", failures[0]),
+			Warnings: failures,
+		}
+	}
+	levels, words := stats.finish()
+	outline := readOutline(pdfReader)
+	continuous := req.BoolOption("continuous", false)
+
+	metadata := map[string]string{
+		"converter": "pdf2md",
+		"pages":     fmt.Sprintf("%d", numPages),
+	}
+
+	// Document information and form fields are returned as metadata too
+	info := pdfDocumentInfo(pdfReader)
+	for key, value := range info {
+		metadata[key] = value
+	}
+	fields := formFields(pdfReader)
+	if len(fields) > 0 {
+		metadata["form_fields"] = fmt.Sprintf("%d", len(fields))
+		for _, field := range fields {
+			metadata["field."+field.Name] = field.Value
+		}
+	}
+
+	// The second pass reads the pages again and converts them. Running
+	// lines are removed and blocks are built in parallel; images are saved
+	// and outline titles matched one page at a time in page order, so that
+	// file names do not depend on scheduling and each outline entry goes to
+	// its first match. Markdown is written page by page, with no more than
+	// about jobs pages held in memory. JSON and split output are only
+	// written once all pages are converted.
+	collect := format == formatJSON || split != ""
+	window = nil
+	if !collect {
+		window = make(chan struct{}, jobs)
+	}
+	converted := make([]convertedPage, len(selected))
+	saved := make([]chan struct{}, len(selected))
+	matched := make([]chan struct{}, len(selected))
+	for i := range selected {
+		saved[i] = make(chan struct{})
+		matched[i] = make(chan struct{})
+	}
+	done = runPages(len(selected), jobs, window, quit, func(i int) {
+		c := &converted[i]
+		c.result = extractPage(pdfReader, selected[i], images, tree, func(save func()) {
+			if i > 0 {
+				<-saved[i-1]
+			}
+			save()
+			close(saved[i])
+		})
+		page := &c.result.layout
+		if c.result.ok {
+			c.removed = stats.running.remove(page)
+		}
+		if i > 0 {
+			<-matched[i-1]
+		}
+		if c.result.ok {
+			c.outlined = applyOutline(page, outline)
+		}
+		close(matched[i])
+		if !c.result.ok || c.result.err != nil {
+			return
+		}
+
+		// The glyphs of marked content are only needed to build the blocks
+		defer func() { page.Marked = nil }()
+		if tree != nil {
+			if c.blocks, c.tagged = tree.pageBlocks(*page, levels, tables, words); c.tagged {
+				return
+			}
+		}
+		c.blocks = buildBlocks(*page, levels, tables, words)
+	})
+
 	var markdown strings.Builder
+	written := false
+	flush := func() error {
+		if markdown.Len() == 0 {
+			return nil
+		}
+		written = true
+		_, err := req.Output.Write([]byte(markdown.String()))
+		markdown.Reset()
+		return err
+	}
 
-	// Extract text from each page
-	numPages := pdfReader.NumPage()
-	for pageNum := 1; pageNum <= numPages; pageNum++ {
-		page := pdfReader.Page(pageNum)
-		if page.V.IsNull() {
+	// Dates and producers alone, which almost every PDF has, are not worth
+	// a front matter block
+	if !collect && req.BoolOption("front_matter", true) && (info["title"] != "" || info["author"] != "" || info["subject"] != "" || info["keywords"] != "") {
+		writeFrontMatter(&markdown, info)
+	}
+
+	var pages []pageLayout
+	var pageBlocks [][]pdfBlock
+	var warnings []models.Warning
+	var tagged []bool
+	removed, outlined, pagesWritten := 0, 0, 0
+	for i := range selected {
+		<-done[i]
+		c := converted[i]
+		converted[i] = convertedPage{}
+		if c.result.err != nil {
+			return &models.ConvertResponse{
+				Success: false,
+				Error:   fmt.Errorf("failed to extract images: %w", c.result.err),
+			}
+		}
+		warnings = append(warnings, c.result.warnings...)
+		removed += c.removed
+		outlined += c.outlined
+		if !c.result.ok {
+			if window != nil {
+				<-window
+			}
 			continue
 		}
-
-		// Extract text from page
-		text, err := page.GetPlainText(nil)
-		if err != nil {
+		tagged = append(tagged, c.tagged)
+		if collect {
+			pages = append(pages, c.result.layout)
+			pageBlocks = append(pageBlocks, c.blocks)
 			continue
 		}
 
-		// Add page separator for multi-page docs
-		if pageNum > 1 {
-			markdown.WriteString("\n\n---\n\n")
+		if continuous {
+			if pagesWritten > 0 && len(c.blocks) > 0 && (written || markdown.Len() > 0) {
+				markdown.WriteString("\n")
+			}
+			writeBlocks(&markdown, c.blocks)
+		} else {
+			// Add page separator for multi-page docs. The page number is a
+			// comment rather than a heading, so that it does not break the
+			// hierarchy of the headings found in the text.
+			if pagesWritten > 0 {
+				markdown.WriteString("\n---\n\n")
+			}
+
+			markdown.WriteString(fmt.Sprintf("<!-- page %d -->\n\n", c.result.layout.Number))
+			writeBlocks(&markdown, c.blocks)
+		}
+		pagesWritten++
+
+		if err := flush(); err != nil {
+			return &models.ConvertResponse{
+				Success: false,
+				Error:   fmt.Errorf("failed to write output: %w", err),
+			}
+		}
+		<-window
+	}
+
+	metadata["removed_lines"] = fmt.Sprintf("%d", removed)
+	metadata["outline"] = fmt.Sprintf("%d", outlined)
+	if images != nil {
+		metadata["images"] = fmt.Sprintf("%d", len(images.saved))
+		metadata["images_skipped"] = fmt.Sprintf("%d", images.skipped)
+	}
+	reportStructure(metadata, tree, tagged)
+
+	if format == formatJSON {
+		if err := writeJSON(req.Output, pages, pageBlocks, fields, metadata); err != nil {
+			return &models.ConvertResponse{
+				Success: false,
+				Error:   fmt.Errorf("failed to write output: %w", err),
+			}
+		}
+		return &models.ConvertResponse{Success: true, Metadata: metadata, Warnings: warnings}
+	}
+
+	if split != "" {
+		files := splitHeadings(pageBlocks)
+		if split == splitPage {
+			files = splitPages(pages, pageBlocks, numPages)
 		}
+		if err := writeMarkdownFiles(outputDir, files); err != nil {
+			return &models.ConvertResponse{
+				Success: false,
+				Error:   fmt.Errorf("failed to write output: %w", err),
+			}
+		}
+		metadata["files"] = fmt.Sprintf("%d", len(files))
+		return &models.ConvertResponse{Success: true, Metadata: metadata, Warnings: warnings}
+	}
 
-		markdown.WriteString(fmt.Sprintf("## Page %d\n\n", pageNum))
-		markdown.WriteString(text)
-		markdown.WriteString("\n")
+	if len(fields) > 0 {
+		if !continuous && pagesWritten > 0 {
+			markdown.WriteString("\n---\n\n")
+		} else if written || markdown.Len() > 0 {
+			markdown.WriteString("\n")
+		}
+		writeFormFields(&markdown, fields)
 	}
 
 	// Write output
-	_, err = req.Output.Write([]byte(markdown.String()))
-	if err != nil {
+	if err := flush(); err != nil {
 		return &models.ConvertResponse{
 			Success: false,
 			Error:   fmt.Errorf("failed to write output: %w", err),
 		}
 	}
 
-	return &models.ConvertResponse{
-		Success: true,
-		Metadata: map[string]string{
-			"converter": "pdf2md",
-			"pages":     fmt.Sprintf("%d", numPages),
-		},
+	return &models.ConvertResponse{Success: true, Metadata: metadata, Warnings: warnings}
+}
+
+// reportStructure records in metadata whether the blocks were taken from
+// the structure tree of a tagged PDF or found by the layout heuristics
+func reportStructure(metadata map[string]string, tree *structTree, tagged []bool) {
+	count := 0
+	for _, t := range tagged {
+		if t {
+			count++
+		}
+	}
+	metadata["structure"] = "heuristic"
+	if count > 0 {
+		metadata["structure"] = "tagged"
+	}
+	if tree != nil {
+		metadata["tagged_pages"] = fmt.Sprintf("%d", count)
+	}
+}
+
+// pageResult is the layout extracted from a page, or why it was skipped
+type pageResult struct {
+	layout   pageLayout
+	ok       bool
+	warnings []models.Warning
+	err      error // failure to write an image, which fails the conversion
+}
+
+// convertedPage is a page converted in the second pass, with the number of
+// running lines removed from it and of outline entries matched on it
+type convertedPage struct {
+	result   pageResult
+	blocks   []pdfBlock
+	tagged   bool
+	removed  int
+	outlined int
+}
+
+// extractPage reads the lines, images and rectangles of a page, and its
+// marked content if the PDF is tagged, i.e. tree is not nil. It is called concurrently for
+// different pages; saving images is serialized by calling the save function
+// through inTurn, which every call does exactly once.
+func extractPage(reader *pdf.Reader, pageNum int, images *imageExtractor, tree *structTree, inTurn func(save func())) (result pageResult) {
+	page, content, links, err := readPage(reader, pageNum)
+	var lines, placed []textLine
+	var marked map[int][]pdf.Text
+	var found pageImages
+	if err == nil {
+		lines = buildLines(content.Text, links)
+		if tree != nil {
+			marked = markedText(page, content.Text, tree.refs[pageNum])
+		}
+		if images != nil {
+			found = images.findImages(page)
+		}
+	}
+
+	skipped := 0
+	inTurn(func() {
+		if err == nil && images != nil {
+			placed, skipped, result.err = images.saveImages(pageNum, found)
+		}
+	})
+
+	if err != nil {
+		result.warnings = append(result.warnings, models.Warning{Page: pageNum, Message: err.Error()})
+		return result
+	}
+	if skipped > 0 {
+		result.warnings = append(result.warnings, models.Warning{
+			Page:    pageNum,
+			Message: fmt.Sprintf("%d image(s) in unsupported formats skipped", skipped),
+		})
+	}
+
+	box := pageMediaBox(page)
+	result.ok = true
+	result.layout = pageLayout{
+		Number: pageNum,
+		Width:  math.Abs(box.Index(2).Float64() - box.Index(0).Float64()),
+		Height: math.Abs(box.Index(3).Float64() - box.Index(1).Float64()),
+		Lines:  readingOrder(append(lines, placed...)),
+		Rects:  content.Rect,
+		Links:  links,
+		Marked: marked,
+	}
+	return result
+}
+
+// runPages calls fn for the indexes 0 to n-1 on up to jobs goroutines,
+// handing indexes out in order until quit is closed. The channel returned
+// for each index is closed when its call has returned. If window is not
+// nil, an index is only handed out once a value can be sent on window; the
+// caller receives one when it is done with a page, which bounds the pages
+// in progress to the capacity of window.
+func runPages(n, jobs int, window chan<- struct{}, quit <-chan struct{}, fn func(i int)) []chan struct{} {
+	done := make([]chan struct{}, n)
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	next := make(chan int)
+	go func() {
+		defer close(next)
+		for i := 0; i < n; i++ {
+			if window != nil {
+				select {
+				case window <- struct{}{}:
+				case <-quit:
+					return
+				}
+			}
+			select {
+			case next <- i:
+			case <-quit:
+				return
+			}
+		}
+	}()
+	for w := 0; w < min(jobs, n); w++ {
+		go func() {
+			for i := range next {
+				fn(i)
+				close(done[i])
+			}
+		}()
+	}
+	return done
+}
+
+// pdfSource returns the PDF input as an io.ReaderAt and its size. Regular
+// files and in-memory readers are read in place, so large files are not
+// copied into memory; other input such as pipes is read fully first.
+func pdfSource(input io.Reader) (io.ReaderAt, int64, error) {
+	switch src := input.(type) {
+	case *os.File:
+		if info, err := src.Stat(); err == nil && info.Mode().IsRegular() {
+			return src, info.Size(), nil
+		}
+	case interface {
+		io.ReaderAt
+		Size() int64
+	}:
+		return src, src.Size(), nil
+	}
+
+	data, err := io.ReadAll(input)
+	if err != nil {
+		return nil, 0, err
+	}
+	return &bytesReaderAt{data: data}, int64(len(data)), nil
+}
+
+// readPage reads the content and links of a page. The PDF library panics
+// on malformed pages; the panic is returned as an error.
+func readPage(reader *pdf.Reader, pageNum int) (page pdf.Page, content pdf.Content, links []pdfLink, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("malformed page: %v", r)
+		}
+	}()
+
+	page = reader.Page(pageNum)
+	if page.V.IsNull() {
+		return page, content, nil, errors.New("page missing from the page tree")
+	}
+	content = page.Content()
+	content.Text = fixUnicode(page, content.Text)
+	return page, content, pageLinks(page), nil
+}
+
+// pageMediaBox returns the media box of a page, which may be inherited from
+// the page tree
+func pageMediaBox(page pdf.Page) pdf.Value {
+	node := page.V
+	for depth := 0; depth < maxPageTreeDepth && !node.IsNull(); depth++ {
+		if box := node.Key("MediaBox"); box.Len() == 4 {
+			return box
+		}
+		node = node.Key("Parent")
+	}
+	return pdf.Value{}
+}
+
+// pageNumbers maps page objects to their page numbers. Structure elements
+// and outline destinations refer to pages by object, which only the string
+// form of the page dictionary identifies.
+func pageNumbers(reader *pdf.Reader) map[string]int {
+	pages := make(map[string]int)
+	for i := 1; i <= reader.NumPage(); i++ {
+		pages[reader.Page(i).V.String()] = i
 	}
+	return pages
 }
 
 // Name returns the converter name
@@ -2,10 +2,28 @@ package converter
 
 import (
 	"bytes"
+	"crypto/md5"
+	"crypto/rc4"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"codeberg.org/go-pdf/fpdf"
 	"github.com/green-creeper/mdtool/pkg/models"
+	"github.com/ledongthuc/pdf"
 )
 
 func TestPDF2MDConverter_Convert(t *testing.T) {
@@ -35,3 +53,1241 @@ func TestPDF2MDConverter_Convert(t *testing.T) {
 		}
 	})
 }
+
+// renderTestPDF builds a PDF with the core fonts and returns its bytes
+func renderTestPDF(t *testing.T, draw func(pdf *fpdf.Fpdf)) []byte {
+	t.Helper()
+
+	doc := fpdf.New("P", "mm", "A4", "")
+	doc.AddPage()
+	draw(doc)
+
+	var buf bytes.Buffer
+	if err := doc.Output(&buf); err != nil {
+		t.Fatalf("failed to generate PDF: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// convertTestPDF runs pdf2md on data with the given options
+func convertTestPDF(t *testing.T, data []byte, options map[string]interface{}) string {
+	t.Helper()
+
+	var output bytes.Buffer
+	resp := NewPDF2MDConverter().Convert(&models.ConvertRequest{
+		Input:   bytes.NewReader(data),
+		Output:  &output,
+		Options: options,
+	})
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+	return output.String()
+}
+
+func TestPDF2MDConverter_Headings(t *testing.T) {
+	data := renderTestPDF(t, func(doc *fpdf.Fpdf) {
+		doc.SetFont("Helvetica", "", 24)
+		doc.Cell(0, 12, "Annual Report")
+		doc.Ln(16)
+		doc.SetFont("Helvetica", "", 18)
+		doc.Cell(0, 10, "Overview")
+		doc.Ln(12)
+		doc.SetFont("Helvetica", "", 11)
+		doc.MultiCell(0, 5, "Revenue grew in every region this year. The board approved the new plan and the budget for the next year.", "", "", false)
+		doc.Ln(6)
+		doc.SetFont("Helvetica", "", 14)
+		doc.Cell(0, 8, "Outlook")
+		doc.Ln(10)
+		doc.SetFont("Helvetica", "", 11)
+		doc.MultiCell(0, 5, "Growth is expected to continue.", "", "", false)
+	})
+
+	markdown := convertTestPDF(t, data, nil)
+
+	for _, want := range []string{
+		"\n# Annual Report\n",
+		"\n## Overview\n",
+		"\n### Outlook\n",
+		"\nGrowth is expected to continue.\n",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected %q in output:\n%s", want, markdown)
+		}
+	}
+	if strings.Contains(markdown, "# Revenue") {
+		t.Errorf("Body text detected as heading:\n%s", markdown)
+	}
+	// The page is not a heading of its own above the detected ones
+	if !strings.HasPrefix(markdown, "<!-- page 1 -->\n\n# Annual Report\n") {
+		t.Errorf("Expected the title to be the first heading:\n%s", markdown)
+	}
+}
+
+func TestPDF2MDConverter_Styles(t *testing.T) {
+	data := renderTestPDF(t, func(doc *fpdf.Fpdf) {
+		doc.SetFont("Helvetica", "", 11)
+		doc.Write(5, "Plain ")
+		doc.SetFont("Helvetica", "B", 11)
+		doc.Write(5, "strong")
+		doc.SetFont("Helvetica", "", 11)
+		doc.Write(5, " and ")
+		doc.SetFont("Helvetica", "I", 11)
+		doc.Write(5, "slanted")
+		doc.SetFont("Helvetica", "", 11)
+		doc.Write(5, " text with ")
+		doc.SetFont("Courier", "", 11)
+		doc.Write(5, "go test")
+		doc.SetFont("Helvetica", "", 11)
+		doc.Write(5, " inline.")
+		doc.Ln(12)
+
+		doc.SetFont("Courier", "", 10)
+		doc.Cell(0, 5, "if ok {")
+		doc.Ln(5)
+		doc.SetX(doc.GetX() + 8.5)
+		doc.Cell(0, 5, "run()")
+		doc.Ln(5)
+		doc.Cell(0, 5, "}")
+	})
+
+	markdown := convertTestPDF(t, data, nil)
+
+	for _, want := range []string{
+		"Plain **strong** and *slanted* text with `go test` inline.",
+		"```\nif ok {\n    run()\n}\n```\n",
+	} {
+		if !strings.Contains(markdown, want) {
+			t.Errorf("Expected %q in output:\n%s", want, markdown)
+		}
+	}
+}
+
+func TestStyleOf(t *testing.T) {
+	tests := []struct {
+		font string
+		want fontStyle
+	}{
+		{"Helvetica", fontStyle{}},
+		{"Helvetica-Bold", fontStyle{Bold: true}},
+		{"TimesNewRoman,Italic", fontStyle{Italic: true}},
+		{"ABCDEF+Arial-BoldItalicMT", fontStyle{Bold: true, Italic: true}},
+		{"Courier-Oblique", fontStyle{Italic: true, Mono: true}},
+		{"utf8dejavuB", fontStyle{Bold: true}},
+		{"utf8dejavumono", fontStyle{Mono: true}},
+		{"ABCDEF+SourceCodePro-Bold", fontStyle{Bold: true, Mono: true}},
+		{"FiraCode", fontStyle{Mono: true}},
+		{"ArialUnicodeMS", fontStyle{}},
+		{"Lucida Sans Unicode", fontStyle{}},
+		{"MonotypeCorsiva", fontStyle{}},
+		{"MonotypeGaramond-Bold", fontStyle{Bold: true}},
+		{"DejaVuSansMono-Bold", fontStyle{Bold: true, Mono: true}},
+		{"CourierNewPSMT", fontStyle{Mono: true}},
+		{"LMMono10-Regular", fontStyle{Mono: true}},
+		{"utf8dejavumonoB", fontStyle{Bold: true, Mono: true}},
+	}
+
+	for _, tt := range tests {
+		if got := styleOf(tt.font); got != tt.want {
+			t.Errorf("styleOf(%q) = %+v, want %+v", tt.font, got, tt.want)
+		}
+	}
+}
+
+func TestPDF2MDConverter_Tables(t *testing.T) {
+	data := renderTestPDF(t, func(doc *fpdf.Fpdf) {
+		doc.SetFont("Helvetica", "", 11)
+		doc.MultiCell(0, 5, "Quarterly results are shown below.", "", "", false)
+		doc.Ln(4)
+		rows := [][]string{
+			{"Region", "Q1", "Q2"},
+			{"North", "1,200", "1,350"},
+			{"South", "980", "1,010"},
+		}
+		for _, row := range rows {
+			doc.CellFormat(60, 6, row[0], "", 0, "L", false, 0, "")
+			doc.CellFormat(30, 6, row[1], "", 0, "R", false, 0, "")
+			doc.CellFormat(30, 6, row[2], "", 1, "R", false, 0, "")
+		}
+	})
+
+	want := "| Region | Q1 | Q2 |\n| --- | --- | --- |\n| North | 1,200 | 1,350 |\n| South | 980 | 1,010 |\n"
+
+	markdown := convertTestPDF(t, data, nil)
+	if !strings.Contains(markdown, want) {
+		t.Errorf("Expected table in output:\n%s", markdown)
+	}
+	if strings.Contains(markdown, "| Quarterly") {
+		t.Errorf("Paragraph detected as table row:\n%s", markdown)
+	}
+
+	markdown = convertTestPDF(t, data, map[string]interface{}{"tables": "off"})
+	if strings.Contains(markdown, "|") {
+		t.Errorf("Expected no table with tables=off:\n%s", markdown)
+	}
+}
+
+func TestPDF2MDConverter_TableRulings(t *testing.T) {
+	// Cells drawn with borders are split at the rulings even when the gap is small
+	data := renderTestPDF(t, func(doc *fpdf.Fpdf) {
+		doc.SetFont("Helvetica", "", 10)
+		for _, row := range [][]string{{"Name", "Value"}, {"alpha", "1"}} {
+			doc.CellFormat(14, 6, row[0], "1", 0, "L", false, 0, "")
+			doc.CellFormat(14, 6, row[1], "1", 1, "L", false, 0, "")
+		}
+	})
+
+	markdown := convertTestPDF(t, data, nil)
+	if !strings.Contains(markdown, "| Name | Value |\n| --- | --- |\n| alpha | 1 |\n") {
+		t.Errorf("Expected table in output:\n%s", markdown)
+	}
+}
+
+func TestPDF2MDConverter_InvalidTablesMode(t *testing.T) {
+	var output bytes.Buffer
+	resp := NewPDF2MDConverter().Convert(&models.ConvertRequest{
+		Input:   bytes.NewReader(renderTestPDF(t, func(doc *fpdf.Fpdf) {})),
+		Output:  &output,
+		Options: map[string]interface{}{"tables": "sometimes"},
+	})
+	if resp.Success || !strings.Contains(resp.Error.Error(), "invalid tables mode") {
+		t.Errorf("Expected invalid tables mode error, got %v", resp.Error)
+	}
+}
+
+func TestPDF2MDConverter_ReadingOrder(t *testing.T) {
+	left := []string{
+		"The first column starts here and",
+		"continues with a second line that",
+		"ends the first column of the page.",
+	}
+	right := []string{
+		"The second column begins after it",
+		"and keeps going for another line",
+		"until the end of the second column.",
+	}
+
+	// Draw both columns line by line, as many generators do
+	data := renderTestPDF(t, func(doc *fpdf.Fpdf) {
+		doc.SetFont("Helvetica", "B", 16)
+		doc.Text(10, 20, "A Paper Spanning Both Columns")
+		doc.SetFont("Helvetica", "", 10)
+		for i := range left {
+			y := 35 + float64(i)*5
+			doc.Text(10, y, left[i])
+			doc.Text(110, y, right[i])
+		}
+		doc.Text(10, 60, "A full-width conclusion follows both columns and closes the page.")
+	})
+
+	markdown := convertTestPDF(t, data, nil)
+
+	want := []string{"# A Paper Spanning Both Columns"}
+	want = append(want, left...)
+	want = append(want, right...)
+	want = append(want, "A full-width conclusion")
+	pos := 0
+	for _, text := range want {
+		i := strings.Index(markdown[pos:], text)
+		if i < 0 {
+			t.Fatalf("Expected %q after position %d in output:\n%s", text, pos, markdown)
+		}
+		pos += i + len(text)
+	}
+}
+
+func TestRemoveRunningLines(t *testing.T) {
+	line := func(text string, y float64) textLine {
+		return textLine{Runs: []textRun{{Text: text}}, Y: y}
+	}
+	page := func(lines ...textLine) pageLayout {
+		return pageLayout{Lines: lines}
+	}
+	texts := func(p pageLayout) string {
+		var parts []string
+		for _, l := range p.Lines {
+			parts = append(parts, l.Text())
+		}
+		return strings.Join(parts, ";")
+	}
+
+	// Numbers that do not recur are content, even at the edge of a page
+	pages := []pageLayout{page(line("Totals", 700), line("12", 60))}
+	if removed := removeRunningLines(pages); removed != 0 || texts(pages[0]) != "Totals;12" {
+		t.Errorf("Expected a one-page document to be kept, got %q", texts(pages[0]))
+	}
+
+	// Roman page numbers at the same position on every page are removed
+	pages = []pageLayout{
+		page(line("Preface", 700), line("v", 400), line("i", 40)),
+		page(line("Thanks", 700), line("ii", 40)),
+		page(line("Contents", 700), line("iii", 40)),
+	}
+	if removed := removeRunningLines(pages); removed != 3 {
+		t.Errorf("Expected 3 page numbers removed, got %d", removed)
+	}
+	if got := texts(pages[0]); got != "Preface;v" {
+		t.Errorf("Expected the numeral in the text to be kept, got %q", got)
+	}
+
+	// On short pages every line is an edge line: numbered chapter titles
+	// are kept while the footer with the page number is removed
+	pages = []pageLayout{
+		page(line("Chapter 1", 700), line("Q1 Results", 650), line("Chapter 1 - page 1", 40)),
+		page(line("Chapter 2", 700), line("Q2 Results", 650), line("Chapter 2 - page 2", 40)),
+		page(line("Chapter 3", 700), line("Q3 Results", 650), line("Chapter 3 - page 3", 40)),
+	}
+	if removed := removeRunningLines(pages); removed != 3 {
+		t.Errorf("Expected 3 footers removed, got %d", removed)
+	}
+	if got := texts(pages[1]); got != "Chapter 2;Q2 Results" {
+		t.Errorf("Expected the numbered titles to be kept, got %q", got)
+	}
+}
+
+func TestPDF2MDConverter_RunningHeaders(t *testing.T) {
+	bodies := []string{"Sales grew strongly.", "Costs stayed flat.", "Profit doubled."}
+	data := renderTestPDF(t, func(doc *fpdf.Fpdf) {
+		doc.SetHeaderFunc(func() {
+			doc.SetFont("Helvetica", "", 9)
+			doc.Text(10, 10, "ACME Corp - Annual Report 2024")
+		})
+		doc.SetFooterFunc(func() {
+			doc.SetFont("Helvetica", "", 9)
+			doc.Text(100, 287, fmt.Sprintf("Page %d of 3", doc.PageNo()))
+		})
+		for i, body := range bodies {
+			if i > 0 {
+				doc.AddPage()
+			}
+			doc.SetFont("Helvetica", "", 11)
+			doc.SetY(30)
+			doc.MultiCell(0, 5, body, "", "", false)
+		}
+	})
+
+	markdown := convertTestPDF(t, data, nil)
+	if strings.Contains(markdown, "ACME") || strings.Contains(markdown, "of 3") {
+		t.Errorf("Expected running header and footer to be removed:\n%s", markdown)
+	}
+	for _, body := range bodies {
+		if !strings.Contains(markdown, body) {
+			t.Errorf("Expected %q in output:\n%s", body, markdown)
+		}
+	}
+	if !strings.Contains(markdown, "<!-- page 2 -->") || !strings.Contains(markdown, "\n---\n") {
+		t.Errorf("Expected page separators by default:\n%s", markdown)
+	}
+
+	markdown = convertTestPDF(t, data, map[string]interface{}{"continuous": true})
+	want := "Sales grew strongly.\n\nCosts stayed flat.\n\nProfit doubled.\n"
+	if markdown != want {
+		t.Errorf("Continuous output = %q, want %q", markdown, want)
+	}
+}
+
+// renderChapters builds a PDF with one chapter heading and paragraph per page
+func renderChapters(t *testing.T, chapters ...string) []byte {
+	t.Helper()
+
+	return renderTestPDF(t, func(doc *fpdf.Fpdf) {
+		for i, chapter := range chapters {
+			if i > 0 {
+				doc.AddPage()
+			}
+			doc.SetFont("Helvetica", "B", 20)
+			doc.Cell(0, 10, chapter)
+			doc.Ln(14)
+			doc.SetFont("Helvetica", "", 11)
+			doc.MultiCell(0, 5, "This chapter is about "+strings.ToLower(chapter)+" and nothing else.", "", "", false)
+		}
+	})
+}
+
+func TestPDF2MDConverter_Pages(t *testing.T) {
+	data := renderChapters(t, "Apples", "Bananas", "Cherries", "Dates")
+
+	markdown := convertTestPDF(t, data, map[string]interface{}{"pages": "2-3"})
+	if strings.Contains(markdown, "Apples") || strings.Contains(markdown, "Dates") {
+		t.Errorf("Expected only pages 2 and 3:\n%s", markdown)
+	}
+	if !strings.Contains(markdown, "<!-- page 2 -->") || !strings.Contains(markdown, "# Cherries") {
+		t.Errorf("Expected pages 2 and 3:\n%s", markdown)
+	}
+}
+
+func TestPDF2MDConverter_Split(t *testing.T) {
+	data := renderChapters(t, "Apples", "Bananas", "Cherries")
+
+	tests := []struct {
+		split string
+		want  map[string]string
+	}{
+		{
+			split: "page",
+			want: map[string]string{
+				"page-1.md": "# Apples\n",
+				"page-3.md": "# Cherries\n",
+			},
+		},
+		{
+			split: "heading",
+			want: map[string]string{
+				"01-apples.md":   "# Apples\n",
+				"02-bananas.md":  "bananas and nothing else",
+				"03-cherries.md": "# Cherries\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.split, func(t *testing.T) {
+			dir := t.TempDir()
+			var output bytes.Buffer
+			resp := NewPDF2MDConverter().Convert(&models.ConvertRequest{
+				Input:   bytes.NewReader(data),
+				Output:  &output,
+				Options: map[string]interface{}{"split": tt.split, "output_dir": dir},
+			})
+			if !resp.Success {
+				t.Fatalf("Convert() failed: %v", resp.Error)
+			}
+			if resp.Metadata["files"] != "3" {
+				t.Errorf("Expected 3 files, got %s", resp.Metadata["files"])
+			}
+			for name, want := range tt.want {
+				content, err := os.ReadFile(filepath.Join(dir, name))
+				if err != nil {
+					t.Fatalf("Expected file %s: %v", name, err)
+				}
+				if !strings.Contains(string(content), want) {
+					t.Errorf("%s = %q, want it to contain %q", name, content, want)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePageRanges(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []int
+		wantErr bool
+	}{
+		{spec: "", want: []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}},
+		{spec: "3-5,2,4", want: []int{2, 3, 4, 5}},
+		{spec: "9-", want: []int{9, 10}},
+		{spec: "0", wantErr: true},
+		{spec: "5-3", wantErr: true},
+		{spec: "8-12", wantErr: true},
+		{spec: "a-b", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := parsePageRanges(tt.spec, 10)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parsePageRanges(%q) error = %v, wantErr %v", tt.spec, err, tt.wantErr)
+			continue
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) && !tt.wantErr {
+			t.Errorf("parsePageRanges(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestPDF2MDConverter_Images(t *testing.T) {
+	pattern := image.NewRGBA(image.Rect(0, 0, 16, 8))
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 16; x++ {
+			pattern.Set(x, y, color.RGBA{uint8(x * 16), uint8(y * 32), 128, 255})
+		}
+	}
+	var jpegData, pngData bytes.Buffer
+	if err := jpeg.Encode(&jpegData, pattern, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(&pngData, pattern); err != nil {
+		t.Fatal(err)
+	}
+
+	data := renderTestPDF(t, func(pdf *fpdf.Fpdf) {
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.Text(20, 30, "Text before the photo.")
+		pdf.RegisterImageOptionsReader("photo", fpdf.ImageOptions{ImageType: "JPG"}, bytes.NewReader(jpegData.Bytes()))
+		pdf.ImageOptions("photo", 20, 40, 40, 20, false, fpdf.ImageOptions{}, 0, "")
+		pdf.Text(20, 80, "Text between the images.")
+		pdf.RegisterImageOptionsReader("chart", fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(pngData.Bytes()))
+		pdf.ImageOptions("chart", 20, 90, 40, 20, false, fpdf.ImageOptions{}, 0, "")
+		pdf.Text(20, 130, "Text after the images.")
+	})
+
+	dir := filepath.Join(t.TempDir(), "assets")
+	output := convertTestPDF(t, data, map[string]interface{}{"assets_dir": dir, "assets_name": "report"})
+
+	order := []string{
+		"Text before the photo.",
+		"![](assets/report-page1-img1.jpg)",
+		"Text between the images.",
+		"![](assets/report-page1-img2.png)",
+		"Text after the images.",
+	}
+	last := -1
+	for _, want := range order {
+		i := strings.Index(output, want)
+		if i < 0 {
+			t.Fatalf("Expected output to contain %q, got:\n%s", want, output)
+		}
+		if i < last {
+			t.Errorf("Expected %q after the previous line, got:\n%s", want, output)
+		}
+		last = i
+	}
+
+	// JPEG data is copied unchanged
+	saved, err := os.ReadFile(filepath.Join(dir, "report-page1-img1.jpg"))
+	if err != nil {
+		t.Fatalf("Expected extracted JPEG: %v", err)
+	}
+	if !bytes.Equal(saved, jpegData.Bytes()) {
+		t.Error("Expected the extracted JPEG to match the embedded one")
+	}
+
+	// PNG data is decoded and re-encoded losslessly
+	file, err := os.Open(filepath.Join(dir, "report-page1-img2.png"))
+	if err != nil {
+		t.Fatalf("Expected extracted PNG: %v", err)
+	}
+	defer file.Close()
+	decoded, err := png.Decode(file)
+	if err != nil {
+		t.Fatalf("Failed to decode extracted PNG: %v", err)
+	}
+	for _, p := range []image.Point{{0, 0}, {7, 3}, {15, 7}} {
+		r1, g1, b1, _ := decoded.At(p.X, p.Y).RGBA()
+		r2, g2, b2, _ := pattern.At(p.X, p.Y).RGBA()
+		if r1 != r2 || g1 != g2 || b1 != b2 {
+			t.Errorf("Pixel %v = %v, want %v", p, decoded.At(p.X, p.Y), pattern.At(p.X, p.Y))
+		}
+	}
+
+	// Without an assets directory images are left out
+	if output := convertTestPDF(t, data, nil); strings.Contains(output, "![]") {
+		t.Errorf("Expected no image references without assets_dir, got:\n%s", output)
+	}
+}
+
+func TestPDF2MDConverter_ImagesWithoutStreamOffset(t *testing.T) {
+	var jpegData, pngData bytes.Buffer
+	if err := jpeg.Encode(&jpegData, image.NewGray(image.Rect(0, 0, 8, 8)), nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(&pngData, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	data := renderTestPDF(t, func(doc *fpdf.Fpdf) {
+		doc.SetFont("Helvetica", "", 11)
+		doc.Text(20, 30, "Text with images.")
+		doc.RegisterImageOptionsReader("photo", fpdf.ImageOptions{ImageType: "JPG"}, bytes.NewReader(jpegData.Bytes()))
+		doc.ImageOptions("photo", 20, 40, 20, 20, false, fpdf.ImageOptions{}, 0, "")
+		doc.RegisterImageOptionsReader("chart", fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(pngData.Bytes()))
+		doc.ImageOptions("chart", 20, 70, 20, 20, false, fpdf.ImageOptions{}, 0, "")
+	})
+
+	// A reader version whose stream values do not end in "@offset"
+	streamString = func(v pdf.Value) string {
+		return streamOffsetRegex.ReplaceAllString(v.String(), "")
+	}
+	t.Cleanup(func() { streamString = pdf.Value.String })
+
+	// The JPEG cannot be copied and the reader does not decode the PNG
+	// predictor, so both are skipped rather than failing the conversion
+	dir := filepath.Join(t.TempDir(), "assets")
+	var output bytes.Buffer
+	resp := NewPDF2MDConverter().Convert(&models.ConvertRequest{
+		Input:   bytes.NewReader(data),
+		Output:  &output,
+		Options: map[string]interface{}{"assets_dir": dir},
+	})
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+	if resp.Metadata["images_skipped"] != "2" || strings.Contains(output.String(), "![]") {
+		t.Errorf("Expected both images skipped, got %s skipped:\n%s", resp.Metadata["images_skipped"], output.String())
+	}
+	if !strings.Contains(output.String(), "Text with images.") {
+		t.Errorf("Expected the text to be kept, got:\n%s", output.String())
+	}
+}
+
+// countingWriter counts the writes made to it
+type countingWriter struct {
+	bytes.Buffer
+	writes int
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.writes++
+	return w.Buffer.Write(p)
+}
+
+func TestPDF2MDConverter_Jobs(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, image.NewGray(image.Rect(0, 0, 8, 8))); err != nil {
+		t.Fatal(err)
+	}
+	data := renderTestPDF(t, func(doc *fpdf.Fpdf) {
+		doc.RegisterImageOptionsReader("logo", fpdf.ImageOptions{ImageType: "PNG"}, bytes.NewReader(pngData.Bytes()))
+		for i := 1; i <= 12; i++ {
+			if i > 1 {
+				doc.AddPage()
+			}
+			doc.SetFont("Helvetica", "", 11)
+			doc.Text(20, float64(30+10*i), fmt.Sprintf("Text of page %d.", i))
+			if i%3 == 0 {
+				doc.ImageOptions("logo", 20, float64(40+10*i), 20, 20, false, fpdf.ImageOptions{}, 0, "")
+			}
+		}
+	})
+	path := filepath.Join(t.TempDir(), "input.pdf")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	convert := func(input io.Reader, jobs int) (*countingWriter, string) {
+		dir := filepath.Join(t.TempDir(), "assets")
+		output := &countingWriter{}
+		resp := NewPDF2MDConverter().Convert(&models.ConvertRequest{
+			Input:   input,
+			Output:  output,
+			Options: map[string]interface{}{"jobs": jobs, "assets_dir": dir},
+		})
+		if !resp.Success {
+			t.Fatalf("Convert() with %d jobs failed: %v", jobs, resp.Error)
+		}
+		return output, resp.Metadata["images"]
+	}
+
+	sequential, images := convert(bytes.NewReader(data), 1)
+	if images != "1" {
+		t.Errorf("Expected the repeated image to be saved once, got %s", images)
+	}
+	if !strings.Contains(sequential.String(), "<!-- page 12 -->\n\nText of page 12.") {
+		t.Errorf("Expected all pages, got:\n%s", sequential.String())
+	}
+	if strings.Count(sequential.String(), "![](assets/page3-img1.png)") != 4 {
+		t.Errorf("Expected every reference to use the name from page 3, got:\n%s", sequential.String())
+	}
+
+	// Output is identical whatever the number of workers and the input type
+	for _, jobs := range []int{4, 12, 0} {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parallel, _ := convert(file, jobs)
+		file.Close()
+		if parallel.String() != sequential.String() {
+			t.Errorf("Output with %d jobs differs:\n%s\nwant:\n%s", jobs, parallel.String(), sequential.String())
+		}
+		// Pages are written as they are done rather than all at once
+		if parallel.writes < 12 {
+			t.Errorf("Expected a write per page with %d jobs, got %d writes", jobs, parallel.writes)
+		}
+	}
+
+	// Input that is not a file is read into memory first
+	piped, _ := convert(io.MultiReader(bytes.NewReader(data)), 4)
+	if piped.String() != sequential.String() {
+		t.Errorf("Output from a plain reader differs:\n%s", piped.String())
+	}
+}
+
+func TestRunPagesWindow(t *testing.T) {
+	// Pages are handed out only while fewer than the window's capacity are
+	// in progress, however far ahead the workers could run
+	quit := make(chan struct{})
+	defer close(quit)
+	window := make(chan struct{}, 3)
+	var mu sync.Mutex
+	started, released, most := 0, 0, 0
+	done := runPages(50, 8, window, quit, func(i int) {
+		mu.Lock()
+		defer mu.Unlock()
+		started++
+		most = max(most, started-released)
+	})
+	for i := range done {
+		<-done[i]
+		mu.Lock()
+		released++
+		mu.Unlock()
+		<-window
+	}
+	if started != 50 || most > 3 {
+		t.Errorf("Expected 50 pages with at most 3 in progress, got %d pages and %d in progress", started, most)
+	}
+}
+
+func TestPDF2MDConverter_Outline(t *testing.T) {
+	data := renderTestPDF(t, func(pdf *fpdf.Fpdf) {
+		pdf.SetFont("Helvetica", "", 18)
+		pdf.Text(20, 20, "Big Title Not In The Outline")
+		pdf.Bookmark("1. Getting Started", 0, 30)
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.Text(20, 35, "1 Getting Started")
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.Text(20, 45, "Body text of the first chapter.")
+		pdf.Bookmark("Installing on a very long and wrapped heading line", 1, 55)
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.Text(20, 60, "Installing on a very long")
+		pdf.Text(20, 65, "and wrapped heading line")
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.Text(20, 75, "Body text of the section.")
+	})
+
+	// Lines the outline does not cover fall back to font sizes
+	output := convertTestPDF(t, data, nil)
+	for _, want := range []string{
+		"# 1 Getting Started\n",
+		"## Installing on a very long and wrapped heading line\n",
+		"# Big Title Not In The Outline\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestPDF2MDConverter_OutlineContents(t *testing.T) {
+	data := renderTestPDF(t, func(pdf *fpdf.Fpdf) {
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.Text(20, 20, "Contents")
+		pdf.Text(20, 30, "Introduction")
+		pdf.Text(20, 40, "Methods")
+		for _, chapter := range []string{"Introduction", "Methods"} {
+			pdf.AddPage()
+			pdf.Bookmark(chapter, 0, 0)
+			pdf.SetFont("Helvetica", "B", 11)
+			pdf.Text(20, 20, chapter)
+			pdf.SetFont("Helvetica", "", 11)
+			pdf.Text(20, 30, "Body text of the "+strings.ToLower(chapter)+" chapter.")
+		}
+	})
+
+	// The entries of the table of contents come before the pages the
+	// outline points to, so only the chapter titles become headings
+	output := convertTestPDF(t, data, map[string]interface{}{"continuous": true})
+	want := "Contents\n\nIntroduction\n\nMethods\n\n# Introduction\n\nBody text of the introduction chapter.\n\n# Methods\n\nBody text of the methods chapter.\n"
+	if output != want {
+		t.Errorf("Output = %q, want %q", output, want)
+	}
+}
+
+func TestPDF2MDConverter_Links(t *testing.T) {
+	data := renderTestPDF(t, func(pdf *fpdf.Fpdf) {
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.SetXY(20, 20)
+		pdf.Write(5, "See the ")
+		pdf.WriteLinkString(5, "project site", "https://example.com/docs (v2)")
+		pdf.Write(5, " for details.")
+		pdf.Ln(10)
+		pdf.SetFont("Helvetica", "B", 11)
+		pdf.WriteLinkString(5, "Bold link", "https://example.com/bold")
+	})
+
+	output := convertTestPDF(t, data, nil)
+	for _, want := range []string{
+		"See the [project site](https://example.com/docs%20%28v2%29) for details.",
+		"[**Bold link**](https://example.com/bold)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+// encryptTestPDF encrypts a PDF written by fpdf with 128-bit RC4 (revision 3
+// of the standard security handler), using the same user and owner password
+func encryptTestPDF(t *testing.T, data []byte, password string) []byte {
+	t.Helper()
+
+	padding := []byte{
+		0x28, 0xBF, 0x4E, 0x5E, 0x4E, 0x75, 0x8A, 0x41, 0x64, 0x00, 0x4E, 0x56, 0xFF, 0xFA, 0x01, 0x08,
+		0x2E, 0x2E, 0x00, 0xB6, 0xD0, 0x68, 0x3E, 0x80, 0x2F, 0x0C, 0xA9, 0xFE, 0x64, 0x53, 0x69, 0x7A,
+	}
+	padded := append([]byte(password), padding...)[:32]
+	id := make([]byte, 16)
+	permissions := []byte{0xFC, 0xFF, 0xFF, 0xFF} // -4, everything allowed
+	rc4Rounds := func(key, data []byte) []byte {
+		out := append([]byte(nil), data...)
+		for i := 0; i < 20; i++ {
+			k := make([]byte, len(key))
+			for j := range key {
+				k[j] = key[j] ^ byte(i)
+			}
+			c, _ := rc4.NewCipher(k)
+			c.XORKeyStream(out, out)
+		}
+		return out
+	}
+	md5Rounds := func(data []byte) []byte {
+		sum := md5.Sum(data)
+		for i := 0; i < 50; i++ {
+			sum = md5.Sum(sum[:])
+		}
+		return sum[:]
+	}
+
+	owner := rc4Rounds(md5Rounds(padded), padded)
+	key := md5Rounds(bytes.Join([][]byte{padded, owner, permissions, id}, nil))
+	check := md5.Sum(append(append([]byte(nil), padding...), id...))
+	user := append(rc4Rounds(key, check[:]), make([]byte, 16)...)
+
+	file, err := parsePDFFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, obj := range file.objects {
+		loc := streamRegex.FindIndex(obj.body)
+		length := lengthRegex.FindSubmatch(obj.body)
+		if loc == nil || length == nil {
+			continue
+		}
+		n, _ := strconv.Atoi(string(length[1]))
+		objKey := md5.Sum(append(append([]byte(nil), key...), byte(obj.num), byte(obj.num>>8), byte(obj.num>>16), 0, 0))
+		c, _ := rc4.NewCipher(objKey[:])
+		stream := file.objects[i].body[loc[1] : loc[1]+n]
+		c.XORKeyStream(stream, stream)
+	}
+
+	// The file ID is part of the key, so the one written is replaced by zeros
+	out := regexp.MustCompile(`/ID \[<[0-9a-f]+> <[0-9a-f]+>\]`).ReplaceAll(file.write(), []byte(fmt.Sprintf(
+		"/Encrypt << /Filter /Standard /V 2 /R 3 /Length 128 /P -4 /O <%x> /U <%x> >>\n/ID [<%x> <%x>]", owner, user, id, id)))
+	return out
+}
+
+func TestPDF2MDConverter_Encrypted(t *testing.T) {
+	render := func(protect bool) []byte {
+		return renderTestPDF(t, func(pdf *fpdf.Fpdf) {
+			if protect {
+				pdf.SetProtection(fpdf.CnProtectPrint, "secret", "owner")
+			}
+			pdf.SetFont("Helvetica", "", 11)
+			pdf.Text(20, 30, "Confidential vendor statement.")
+		})
+	}
+	protected := encryptTestPDF(t, render(false), "secret")
+
+	tests := []struct {
+		name     string
+		data     []byte
+		password string
+		wantErr  error
+	}{
+		{name: "password required", data: protected, wantErr: ErrPasswordRequired},
+		{name: "wrong password", data: protected, password: "guess", wantErr: ErrWrongPassword},
+		{name: "corrupt", data: []byte("%PDF-1.4\ngarbage"), wantErr: ErrCorruptPDF},
+		{name: "40-bit key", data: render(true), password: "secret", wantErr: ErrUnsupportedEncryption},
+		{name: "correct password", data: protected, password: "secret"},
+		{name: "empty user password", data: encryptTestPDF(t, render(false), "")},
+	}
+
+	c := NewPDF2MDConverter()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			resp := c.Convert(&models.ConvertRequest{
+				Input:   bytes.NewReader(tt.data),
+				Output:  &output,
+				Options: map[string]interface{}{"password": tt.password},
+			})
+
+			if tt.wantErr != nil {
+				if resp.Success || !errors.Is(resp.Error, tt.wantErr) {
+					t.Fatalf("Convert() error = %v, want %v", resp.Error, tt.wantErr)
+				}
+				return
+			}
+			if !resp.Success {
+				t.Fatalf("Convert() failed: %v", resp.Error)
+			}
+			if !strings.Contains(output.String(), "Confidential vendor statement.") {
+				t.Errorf("Expected decrypted text, got:\n%s", output.String())
+			}
+		})
+	}
+}
+
+func TestPDF2MDConverter_MalformedPage(t *testing.T) {
+	data := renderTestPDF(t, func(pdf *fpdf.Fpdf) {
+		pdf.SetCompression(false)
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.Text(20, 30, "First page text.")
+		pdf.AddPage()
+		pdf.Text(20, 30, "Second page text.")
+	})
+
+	// Claim that the second page is compressed so that reading it fails
+	file, err := parsePDFFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, obj := range file.objects {
+		if bytes.Contains(obj.body, []byte("(Second page text.)")) {
+			file.objects[i].body = bytes.Replace(obj.body, []byte("<<"), []byte("<</Filter /FlateDecode "), 1)
+		}
+	}
+	data = file.write()
+
+	c := NewPDF2MDConverter()
+	var output bytes.Buffer
+	resp := c.Convert(&models.ConvertRequest{Input: bytes.NewReader(data), Output: &output})
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+	if !strings.Contains(output.String(), "First page text.") {
+		t.Errorf("Expected the readable page to be converted, got:\n%s", output.String())
+	}
+	if len(resp.Warnings) != 1 || resp.Warnings[0].Page != 2 {
+		t.Fatalf("Expected one warning for page 2, got %v", resp.Warnings)
+	}
+
+	resp = c.Convert(&models.ConvertRequest{
+		Input:   bytes.NewReader(data),
+		Output:  &output,
+		Options: map[string]interface{}{"strict": true},
+	})
+	if resp.Success || resp.Error == nil || !strings.Contains(resp.Error.Error(), "page 2") {
+		t.Errorf("Expected strict mode to fail on page 2, got error %v", resp.Error)
+	}
+}
+
+func TestPDF2MDConverter_StrictSkippedImage(t *testing.T) {
+	var jpegData bytes.Buffer
+	if err := jpeg.Encode(&jpegData, image.NewGray(image.Rect(0, 0, 4, 4)), nil); err != nil {
+		t.Fatal(err)
+	}
+	data := renderTestPDF(t, func(pdf *fpdf.Fpdf) {
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.Text(20, 30, "Readable text.")
+		pdf.RegisterImageOptionsReader("scan", fpdf.ImageOptions{ImageType: "JPG"}, bytes.NewReader(jpegData.Bytes()))
+		pdf.ImageOptions("scan", 20, 40, 20, 20, false, fpdf.ImageOptions{}, 0, "")
+	})
+
+	// Claim an encoding images cannot be extracted from
+	file, err := parsePDFFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, obj := range file.objects {
+		file.objects[i].body = bytes.Replace(obj.body, []byte("/DCTDecode"), []byte("/JBIG2Decode"), 1)
+	}
+	data = file.write()
+
+	var output bytes.Buffer
+	resp := NewPDF2MDConverter().Convert(&models.ConvertRequest{
+		Input:   bytes.NewReader(data),
+		Output:  &output,
+		Options: map[string]interface{}{"strict": true, "assets_dir": filepath.Join(t.TempDir(), "assets")},
+	})
+	if !resp.Success {
+		t.Fatalf("Expected a skipped image not to fail strict mode, got %v", resp.Error)
+	}
+	if len(resp.Warnings) != 1 || !strings.Contains(resp.Warnings[0].Message, "skipped") {
+		t.Errorf("Expected a warning for the skipped image, got %v", resp.Warnings)
+	}
+	if !strings.Contains(output.String(), "Readable text.") {
+		t.Errorf("Expected the page text, got:\n%s", output.String())
+	}
+}
+
+func TestPDF2MDConverter_Reflow(t *testing.T) {
+	data := renderTestPDF(t, func(pdf *fpdf.Fpdf) {
+		tr := pdf.UnicodeTranslatorFromDescriptor("")
+		pdf.SetFont("Helvetica", "", 11)
+		text := func(x, y float64, s string) { pdf.Text(x, y, tr(s)) }
+
+		text(20, 30, "The conver-")
+		text(20, 35, "sion keeps a well-")
+		text(20, 40, "known layout intact.")
+		// Wider line spacing starts a new paragraph
+		text(20, 46.5, "Second paragraph with a well-known term.")
+
+		text(20, 70, "• First item that wraps")
+		text(24, 75, "onto a second line")
+		text(24, 80, "– Nested item")
+		text(20, 85, "• Third item")
+
+		text(20, 100, "1. Step one")
+		text(20, 105, "2. Step two")
+	})
+
+	output := convertTestPDF(t, data, nil)
+	for _, want := range []string{
+		"The conversion keeps a well-known layout intact.\n\nSecond paragraph with a well-known term.\n",
+		"- First item that wraps onto a second line\n  - Nested item\n- Third item\n",
+		"1. Step one\n2. Step two\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, output)
+		}
+	}
+}
+
+func TestDehyphenate(t *testing.T) {
+	words := map[string]bool{"reflow": true, "e-mail": true}
+	tests := []struct {
+		head, tail string
+		want       bool
+	}{
+		{"re", "flow", true},
+		{"e", "mail", false},
+		{"conver", "sion", true},
+		{"Jean", "Paul", false},
+	}
+	for _, tt := range tests {
+		if got := dehyphenate(tt.head, tt.tail, words); got != tt.want {
+			t.Errorf("dehyphenate(%q, %q) = %v, want %v", tt.head, tt.tail, got, tt.want)
+		}
+	}
+}
+
+func TestParseToUnicode(t *testing.T) {
+	// The identity CMap fpdf writes for Unicode fonts, with a character and
+	// an array range added
+	m := parseToUnicode([]byte(`/CIDInit /ProcSet findresource begin
+12 dict begin
+begincmap
+/CIDSystemInfo
+<</Registry (Adobe)
+/Ordering (UCS)
+/Supplement 0
+>> def
+/CMapName /Adobe-Identity-UCS def
+/CMapType 2 def
+1 begincodespacerange
+<0000> <FFFF>
+endcodespacerange
+1 beginbfchar
+<F001> <00660069>
+endbfchar
+2 beginbfrange
+<0000> <EFFF> <0000>
+<F002> <F003> [<2192> (\041\042)]
+endbfrange
+endcmap
+CMapName currentdict /CMap defineresource pop
+end
+end`))
+
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{"\x00A\x00b", "Ab"},
+		{"\x20\x22\x00 ", "• "},
+		{"\x20\xac", "€"},
+		{"\xf0\x01", "fi"},
+		{"\xf0\x02\xf0\x03", "→™"},
+		{"\xf0\x04", "�"},
+	}
+	for _, tt := range tests {
+		if got := m.Decode(tt.raw); got != tt.want {
+			t.Errorf("Decode(%q) = %q, want %q", tt.raw, got, tt.want)
+		}
+	}
+
+	for _, r := range m.ranges {
+		if want := r.lo == "\x00\x00"; r.misread() != want {
+			t.Errorf("misread() of range %q-%q = %v, want %v", r.lo, r.hi, !want, want)
+		}
+	}
+}
+
+func TestPDF2MDConverter_DocumentInfo(t *testing.T) {
+	data := renderTestPDF(t, func(pdf *fpdf.Fpdf) {
+		pdf.SetTitle("Quarterly Report", true)
+		pdf.SetAuthor("Jane \"JD\" Doe", true)
+		pdf.SetKeywords("sales, finance", true)
+		pdf.SetCreationDate(time.Date(2024, 3, 15, 9, 30, 0, 0, time.UTC))
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.Text(20, 30, "Report body.")
+	})
+
+	c := NewPDF2MDConverter()
+	var output bytes.Buffer
+	resp := c.Convert(&models.ConvertRequest{Input: bytes.NewReader(data), Output: &output})
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+
+	want := "---\ntitle: \"Quarterly Report\"\nauthor: \"Jane \\\"JD\\\" Doe\"\nkeywords: \"sales, finance\"\ndate: \"2024-03-15T09:30:00Z\"\n"
+	if !strings.HasPrefix(output.String(), want) {
+		t.Errorf("Expected front matter %q, got:\n%s", want, output.String())
+	}
+	meta, _ := splitFrontMatter(output.Bytes())
+	if meta["title"] != "Quarterly Report" {
+		t.Errorf("Expected front matter readable by md2pdf, got %v", meta)
+	}
+	if resp.Metadata["title"] != "Quarterly Report" || resp.Metadata["date"] != "2024-03-15T09:30:00Z" {
+		t.Errorf("Expected document information in metadata, got %v", resp.Metadata)
+	}
+}
+
+func TestPDF2MDConverter_FormFields(t *testing.T) {
+	data := renderTestPDF(t, func(pdf *fpdf.Fpdf) {
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.Text(20, 30, "Application form")
+	})
+
+	// fpdf cannot write forms, so the fields are added afterwards
+	file, err := parsePDFFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := file.add("<< /FT /Tx /T (name) /V (Jane Doe) >>\n")
+	city := file.add("<< /FT /Tx /T (city) /V (Berlin | Mitte) >>\n")
+	address := file.add(fmt.Sprintf("<< /T (address) /Kids [%d 0 R] >>\n", city))
+	subscribe := file.add("<< /FT /Btn /T (subscribe) /V /Yes >>\n")
+	for i, obj := range file.objects {
+		if bytes.Contains(obj.body, []byte("/Type /Catalog")) {
+			form := fmt.Sprintf("<< /AcroForm << /Fields [%d 0 R %d 0 R %d 0 R] >> ", name, address, subscribe)
+			file.objects[i].body = bytes.Replace(obj.body, []byte("<<"), []byte(form), 1)
+		}
+	}
+
+	c := NewPDF2MDConverter()
+	var output bytes.Buffer
+	resp := c.Convert(&models.ConvertRequest{Input: bytes.NewReader(file.write()), Output: &output})
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+
+	want := "## Form fields\n\n| Field | Value |\n| --- | --- |\n| name | Jane Doe |\n| address.city | Berlin \\| Mitte |\n| subscribe | Yes |\n"
+	if !strings.Contains(output.String(), want) {
+		t.Errorf("Expected form field table %q, got:\n%s", want, output.String())
+	}
+	if resp.Metadata["form_fields"] != "3" || resp.Metadata["field.address.city"] != "Berlin | Mitte" {
+		t.Errorf("Expected form fields in metadata, got %v", resp.Metadata)
+	}
+}
+
+func TestPDF2MDConverter_JSONFormat(t *testing.T) {
+	data := renderTestPDF(t, func(pdf *fpdf.Fpdf) {
+		pdf.SetFont("Helvetica", "B", 16)
+		pdf.Text(20, 20, "Title")
+		pdf.SetFont("Helvetica", "", 11)
+		pdf.Text(20, 30, "Plain and ")
+		pdf.SetFont("Helvetica", "I", 11)
+		pdf.Text(60, 30, "italic text.")
+	})
+
+	var doc jsonDocument
+	output := convertTestPDF(t, data, map[string]interface{}{"format": "json"})
+	if err := json.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("Expected JSON output, got %v:\n%s", err, output)
+	}
+
+	if len(doc.Pages) != 1 || doc.Pages[0].Width != 595.28 || doc.Pages[0].Height != 841.89 {
+		t.Fatalf("Expected one A4 page, got %+v", doc.Pages)
+	}
+	blocks := doc.Pages[0].Blocks
+	if len(blocks) != 2 || blocks[0].Type != "heading" || blocks[0].Level != 1 || blocks[1].Type != "paragraph" {
+		t.Fatalf("Expected a heading and a paragraph, got %+v", blocks)
+	}
+	runs := blocks[1].Lines[0].Runs
+	if len(runs) != 2 || runs[1].Font != "Helvetica-Oblique" || !runs[1].Italic || runs[1].X <= runs[0].X || runs[1].Size != 11 {
+		t.Errorf("Expected a plain and an italic run, got %+v", runs)
+	}
+	if doc.Metadata["pages"] != "1" {
+		t.Errorf("Expected metadata in the JSON output, got %v", doc.Metadata)
+	}
+
+	for _, options := range []map[string]interface{}{
+		{"format": "xml"},
+		{"format": "json", "split": "page", "output_dir": t.TempDir()},
+	} {
+		resp := NewPDF2MDConverter().Convert(&models.ConvertRequest{
+			Input:   bytes.NewReader(data),
+			Output:  &bytes.Buffer{},
+			Options: options,
+		})
+		if resp.Success {
+			t.Errorf("Convert(%v) expected an error", options)
+		}
+	}
+}
+
+func TestPDF2MDConverter_Tagged(t *testing.T) {
+	data := renderTestPDF(t, func(pdf *fpdf.Fpdf) {
+		pdf.SetFont("Helvetica", "", 11)
+		marked := func(tag string, mcid int, x, y float64, text string) {
+			pdf.RawWriteStr(fmt.Sprintf("/%s <</MCID %d>> BDC\n", tag, mcid))
+			pdf.Text(x, y, text)
+			pdf.RawWriteStr("EMC\n")
+		}
+		pdf.RawWriteStr("/Artifact BMC\n")
+		pdf.Text(20, 15, "Running header")
+		pdf.RawWriteStr("EMC\n")
+		// The heading is set in the body size, so only the tags tell it apart
+		marked("H1", 0, 20, 30, "Introduction")
+		marked("P", 1, 20, 40, "A tagged paragraph that")
+		marked("P", 2, 20, 45, "continues on a second line.")
+		marked("Lbl", 3, 20, 55, "1.")
+		marked("LBody", 4, 26, 55, "First item")
+		marked("TH", 5, 20, 65, "Name")
+		marked("TH", 6, 60, 65, "Value")
+		marked("TD", 7, 20, 70, "Alpha")
+		marked("TD", 8, 60, 70, "1")
+		// Text outside the structure tree is kept where it is on the page
+		pdf.Text(20, 50, "An untagged note.")
+		marked("Span", 9, 20, 80, "An unreferenced footnote.")
+	})
+
+	// fpdf cannot write tagged PDFs, so the structure tree is added afterwards
+	file, err := parsePDFFile(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := 0
+	for _, obj := range file.objects {
+		if regexp.MustCompile(`/Type /Page[^s]`).Match(obj.body) {
+			page = obj.num
+		}
+	}
+	root := file.add(fmt.Sprintf(`<< /Type /StructTreeRoot /RoleMap << /Heading1 /H1 >> /K << /S /Document /Pg %d 0 R /K [
+<< /S /Heading1 /K 0 >>
+<< /S /P /K [1 2] >>
+<< /S /L /K << /S /LI /K [<< /S /Lbl /K 3 >> << /S /LBody /K 4 >>] >> >>
+<< /S /Table /K [<< /S /TR /K [<< /S /TH /K 5 >> << /S /TH /K 6 >>] >> << /S /TR /K [<< /S /TD /K 7 >> << /S /TD /K 8 >>] >>] >>
+] >> >>
+`, page))
+	for i, obj := range file.objects {
+		if bytes.Contains(obj.body, []byte("/Type /Catalog")) {
+			file.objects[i].body = bytes.Replace(obj.body, []byte("<<"), []byte(fmt.Sprintf("<< /StructTreeRoot %d 0 R /MarkInfo << /Marked true >> ", root)), 1)
+		}
+	}
+
+	var output bytes.Buffer
+	resp := NewPDF2MDConverter().Convert(&models.ConvertRequest{
+		Input:   bytes.NewReader(file.write()),
+		Output:  &output,
+		Options: map[string]interface{}{"continuous": true},
+	})
+	if !resp.Success {
+		t.Fatalf("Convert() failed: %v", resp.Error)
+	}
+	want := "# Introduction\n\nA tagged paragraph that continues on a second line.\n\nAn untagged note.\n\n1. First item\n\n| Name | Value |\n| --- | --- |\n| Alpha | 1 |\n\nAn unreferenced footnote.\n"
+	if output.String() != want {
+		t.Errorf("Expected Markdown from the structure tree:\n%s\ngot:\n%s", want, output.String())
+	}
+	if resp.Metadata["structure"] != "tagged" || resp.Metadata["tagged_pages"] != "1" {
+		t.Errorf("Expected the structure tree to be reported in metadata, got %v", resp.Metadata)
+	}
+
+	// Untagged documents fall back to the heuristics
+	resp = NewPDF2MDConverter().Convert(&models.ConvertRequest{Input: bytes.NewReader(data), Output: io.Discard})
+	if !resp.Success || resp.Metadata["structure"] != "heuristic" {
+		t.Errorf("Expected heuristic structure for an untagged PDF, got %v", resp.Metadata)
+	}
+}
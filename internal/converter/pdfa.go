@@ -0,0 +1,433 @@
+package converter
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"codeberg.org/go-pdf/fpdf"
+)
+
+// pdfaCreator is written as the creating application of PDF/A documents
+const pdfaCreator = "mdtool"
+
+// preparePDFA sets the document information and the XMP metadata that
+// PDF/A-2b requires. The Info dictionary and the XMP packet must agree, so
+// both are written from the same values.
+func preparePDFA(pdf *fpdf.Fpdf, meta map[string]string) {
+	now := time.Now().Truncate(time.Second)
+	pdf.SetCreationDate(now)
+	pdf.SetModificationDate(now)
+	pdf.SetCreator(pdfaCreator, false)
+	pdf.SetProducer(pdfaCreator, false)
+	applyDocumentInfo(pdf, meta)
+	info := documentInfo(meta)
+
+	var xmp strings.Builder
+	xmp.WriteString("<?xpacket begin=\"\xef\xbb\xbf\" id=\"W5M0MpCehiHzreSzNTczkc9d\"?>\n")
+	xmp.WriteString(`<x:xmpmeta xmlns:x="adobe:ns:meta/">
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#">
+<rdf:Description rdf:about=""
+ xmlns:pdfaid="http://www.aiim.org/pdfa/ns/id/"
+ xmlns:dc="http://purl.org/dc/elements/1.1/"
+ xmlns:pdf="http://ns.adobe.com/pdf/1.3/"
+ xmlns:xmp="http://ns.adobe.com/xap/1.0/">
+<pdfaid:part>2</pdfaid:part>
+<pdfaid:conformance>B</pdfaid:conformance>
+<dc:format>application/pdf</dc:format>
+`)
+	if title := info.Title; title != "" {
+		fmt.Fprintf(&xmp, "<dc:title><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:title>\n", xmlEscape(title))
+	}
+	if author := info.Author; author != "" {
+		fmt.Fprintf(&xmp, "<dc:creator><rdf:Seq><rdf:li>%s</rdf:li></rdf:Seq></dc:creator>\n", xmlEscape(author))
+	}
+	if subject := info.Subject; subject != "" {
+		fmt.Fprintf(&xmp, "<dc:description><rdf:Alt><rdf:li xml:lang=\"x-default\">%s</rdf:li></rdf:Alt></dc:description>\n", xmlEscape(subject))
+	}
+	if keywords := info.Keywords; keywords != "" {
+		fmt.Fprintf(&xmp, "<pdf:Keywords>%s</pdf:Keywords>\n", xmlEscape(keywords))
+	}
+	// fpdf writes Info dates without a time zone, so the XMP dates omit it too
+	date := now.Format("2006-01-02T15:04:05")
+	fmt.Fprintf(&xmp, "<pdf:Producer>%s</pdf:Producer>\n", pdfaCreator)
+	fmt.Fprintf(&xmp, "<xmp:CreatorTool>%s</xmp:CreatorTool>\n", pdfaCreator)
+	fmt.Fprintf(&xmp, "<xmp:CreateDate>%s</xmp:CreateDate>\n", date)
+	fmt.Fprintf(&xmp, "<xmp:ModifyDate>%s</xmp:ModifyDate>\n", date)
+	xmp.WriteString("</rdf:Description>\n</rdf:RDF>\n</x:xmpmeta>\n<?xpacket end=\"w\"?>")
+
+	pdf.SetXmpMetadata([]byte(xmp.String()))
+}
+
+// xmlEscape escapes s for use as XML character data
+func xmlEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+// pdfObject is an indirect object of a PDF file as written by fpdf
+type pdfObject struct {
+	num  int
+	body []byte // everything between "obj" and "endobj"
+}
+
+// pdfFile is a PDF file split into its indirect objects
+type pdfFile struct {
+	header  []byte
+	objects []pdfObject
+	root    int
+	info    int
+	trailer []byte
+}
+
+var (
+	objHeaderRegex  = regexp.MustCompile(`(?m)^(\d+) 0 obj\s`)
+	lengthRegex     = regexp.MustCompile(`/Length (\d+)`)
+	streamRegex     = regexp.MustCompile(`>>\s*stream\r?\n`)
+	trailerRefRegex = regexp.MustCompile(`/(Root|Info) (\d+) 0 R`)
+)
+
+// parsePDFFile splits a PDF written by fpdf into its objects. fpdf writes
+// objects sequentially without object streams, and stream lengths are direct
+// integers, which keeps this simple.
+func parsePDFFile(data []byte) (*pdfFile, error) {
+	file := &pdfFile{}
+	first := objHeaderRegex.FindIndex(data)
+	if first == nil {
+		return nil, errors.New("no objects found")
+	}
+	file.header = data[:first[0]]
+
+	pos := first[0]
+	for {
+		loc := objHeaderRegex.FindSubmatchIndex(data[pos:])
+		if loc == nil || loc[0] != 0 {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		start := pos + loc[1]
+
+		// Skip stream data by its length so binary content cannot end the object early
+		searchFrom := start
+		endAt := bytes.Index(data[start:], []byte("endobj"))
+		if endAt < 0 {
+			return nil, fmt.Errorf("object %d is not terminated", num)
+		}
+		if stream := streamRegex.FindIndex(data[start : start+endAt]); stream != nil {
+			m := lengthRegex.FindSubmatch(data[start : start+stream[0]])
+			if m == nil {
+				return nil, fmt.Errorf("object %d has no direct stream length", num)
+			}
+			length, _ := strconv.Atoi(string(m[1]))
+			searchFrom = start + stream[1] + length
+			if searchFrom > len(data) {
+				return nil, fmt.Errorf("object %d stream exceeds the file", num)
+			}
+			endAt = bytes.Index(data[searchFrom:], []byte("endobj"))
+			if endAt < 0 {
+				return nil, fmt.Errorf("object %d is not terminated", num)
+			}
+		}
+		end := searchFrom + endAt
+
+		file.objects = append(file.objects, pdfObject{num: num, body: data[start:end]})
+		pos = end + len("endobj")
+		for pos < len(data) && (data[pos] == '\n' || data[pos] == '\r') {
+			pos++
+		}
+	}
+
+	trailerAt := bytes.LastIndex(data, []byte("trailer"))
+	if trailerAt < 0 {
+		return nil, errors.New("no trailer found")
+	}
+	file.trailer = data[trailerAt:]
+	for _, m := range trailerRefRegex.FindAllSubmatch(file.trailer, -1) {
+		n, _ := strconv.Atoi(string(m[2]))
+		if string(m[1]) == "Root" {
+			file.root = n
+		} else {
+			file.info = n
+		}
+	}
+	if file.root == 0 {
+		return nil, errors.New("trailer has no document catalog")
+	}
+	return file, nil
+}
+
+// dictText returns the dictionary part of an object, without any stream data
+func (o pdfObject) dictText() []byte {
+	if loc := streamRegex.FindIndex(o.body); loc != nil {
+		return o.body[:loc[0]+len(">>")]
+	}
+	return o.body
+}
+
+// write serializes the objects with a fresh cross-reference table and a trailer with a file ID
+func (f *pdfFile) write() []byte {
+	sort.Slice(f.objects, func(i, j int) bool { return f.objects[i].num < f.objects[j].num })
+
+	var buf bytes.Buffer
+	buf.Write(f.header)
+	offsets := make(map[int]int)
+	size := 1
+	for _, obj := range f.objects {
+		offsets[obj.num] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n", obj.num)
+		buf.Write(obj.body)
+		buf.WriteString("endobj\n")
+		size = max(size, obj.num+1)
+	}
+
+	id := fmt.Sprintf("%x", md5.Sum(buf.Bytes()))
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", size)
+	for n := 1; n < size; n++ {
+		if off, ok := offsets[n]; ok {
+			fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+		} else {
+			buf.WriteString("0000000000 65535 f \n")
+		}
+	}
+	fmt.Fprintf(&buf, "trailer\n<<\n/Size %d\n/Root %d 0 R\n", size, f.root)
+	if f.info != 0 {
+		fmt.Fprintf(&buf, "/Info %d 0 R\n", f.info)
+	}
+	fmt.Fprintf(&buf, "/ID [<%s> <%s>]\n>>\nstartxref\n%d\n%%%%EOF\n", id, id, xref)
+	return buf.Bytes()
+}
+
+// add appends a new object and returns its number
+func (f *pdfFile) add(body string) int {
+	num := 0
+	for _, obj := range f.objects {
+		num = max(num, obj.num)
+	}
+	num++
+	f.objects = append(f.objects, pdfObject{num: num, body: []byte(body)})
+	return num
+}
+
+// fontNameRegex matches the font names of font and font descriptor dictionaries
+var fontNameRegex = regexp.MustCompile(`/(BaseFont|FontName) /([^\s/<>\[\]()]+)`)
+
+// subsetTagRegex matches the tag that marks font subsets, such as "KQWXRB+"
+var subsetTagRegex = regexp.MustCompile(`^[A-Z]{6}\+`)
+
+// toPDFA rewrites a PDF produced by fpdf into PDF/A-2b form: it embeds the
+// sRGB output intent and the full font programs, marks annotations as
+// printable and adds a file ID
+func toPDFA(data []byte) ([]byte, error) {
+	file, err := parsePDFFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse generated PDF: %w", err)
+	}
+	if err := embedFullFonts(file); err != nil {
+		return nil, err
+	}
+
+	iccNum := file.add(string(flateStream("/N 3", srgbProfile())))
+	intentNum := file.add(fmt.Sprintf("<< /Type /OutputIntent /S /GTS_PDFA1 /OutputConditionIdentifier (sRGB IEC61966-2.1) /Info (sRGB IEC61966-2.1) /DestOutputProfile %d 0 R >>\n", iccNum))
+
+	for i, obj := range file.objects {
+		if obj.num == file.root {
+			dict := obj.dictText()
+			end := bytes.LastIndex(dict, []byte(">>"))
+			if end < 0 {
+				return nil, errors.New("malformed document catalog")
+			}
+			body := append([]byte{}, dict[:end]...)
+			body = append(body, fmt.Sprintf("/OutputIntents [%d 0 R]\n", intentNum)...)
+			body = append(body, dict[end:]...)
+			file.objects[i].body = body
+			continue
+		}
+		// PDF/A requires annotations to be printed
+		if bytes.Contains(obj.dictText(), []byte("/Type /Annot ")) {
+			dict := bytes.ReplaceAll(obj.dictText(), []byte("/Type /Annot "), []byte("/Type /Annot /F 4 "))
+			file.objects[i].body = append(dict, obj.body[len(obj.dictText()):]...)
+		}
+	}
+
+	return file.write(), nil
+}
+
+var (
+	alphaRegex     = regexp.MustCompile(`/(?:CA|ca) ([0-9.]+)`)
+	blendRegex     = regexp.MustCompile(`/BM\s*/(\w+)`)
+	softMaskRegex  = regexp.MustCompile(`/SMask \d+ 0 R`)
+	fontTypeRegex  = regexp.MustCompile(`/Subtype /(Type1|MMType1|TrueType|CIDFontType0|CIDFontType2)\b`)
+	forbiddenRegex = regexp.MustCompile(`/(JavaScript|JS|Launch|Encrypt)\b`)
+)
+
+// validatePDFA checks the rewritten document against the PDF/A-2b rules mdtool
+// can violate and returns an error listing every problem found
+func validatePDFA(data []byte) error {
+	file, err := parsePDFFile(data)
+	if err != nil {
+		return fmt.Errorf("PDF/A validation failed: %w", err)
+	}
+
+	var issues []string
+	if forbiddenRegex.Match(file.trailer) {
+		issues = append(issues, "document is encrypted")
+	}
+	if !bytes.Contains(file.trailer, []byte("/ID")) {
+		issues = append(issues, "trailer has no file identifier")
+	}
+
+	for _, obj := range file.objects {
+		dict := obj.dictText()
+		if obj.num == file.root {
+			if !bytes.Contains(dict, []byte("/OutputIntents")) {
+				issues = append(issues, "catalog has no output intent")
+			}
+			if !bytes.Contains(dict, []byte("/Metadata")) {
+				issues = append(issues, "catalog has no XMP metadata")
+			}
+		}
+		if m := forbiddenRegex.FindSubmatch(dict); m != nil {
+			issues = append(issues, fmt.Sprintf("object %d uses forbidden /%s", obj.num, m[1]))
+		}
+		for _, m := range alphaRegex.FindAllSubmatch(dict, -1) {
+			if alpha, err := strconv.ParseFloat(string(m[1]), 64); err == nil && math.Abs(alpha-1) > 1e-6 {
+				issues = append(issues, fmt.Sprintf("object %d uses transparency", obj.num))
+				break
+			}
+		}
+		if m := blendRegex.FindSubmatch(dict); m != nil && string(m[1]) != "Normal" && string(m[1]) != "Compatible" {
+			issues = append(issues, fmt.Sprintf("object %d uses blend mode %s", obj.num, m[1]))
+		}
+		if softMaskRegex.Match(dict) {
+			issues = append(issues, fmt.Sprintf("object %d uses a soft mask", obj.num))
+		}
+		if bytes.Contains(dict, []byte("/Type /Font")) && fontTypeRegex.Match(dict) && !bytes.Contains(dict, []byte("/FontDescriptor")) {
+			issues = append(issues, fmt.Sprintf("font object %d is not embedded", obj.num))
+		}
+		if bytes.Contains(dict, []byte("/Type /FontDescriptor")) && !bytes.Contains(dict, []byte("/FontFile")) {
+			issues = append(issues, fmt.Sprintf("font descriptor %d has no embedded font program", obj.num))
+		}
+		if bytes.Contains(dict, []byte("/Subtype /Type0")) {
+			if m := fontNameRegex.FindSubmatch(dict); m != nil && subsetTagRegex.Match(m[2]) {
+				issues = append(issues, fmt.Sprintf("font %s is embedded as a subset", m[2]))
+			}
+		}
+	}
+	for _, font := range type0Fonts(file) {
+		fontFile, _ := file.object(font.fontFile)
+		m := length1Regex.FindSubmatch(fontFile.dictText())
+		if program := fontProgram(font.name); program != nil && (m == nil || string(m[1]) != strconv.Itoa(len(program))) {
+			issues = append(issues, fmt.Sprintf("font %s is not embedded in full", font.name))
+		}
+	}
+
+	if len(issues) > 0 {
+		return fmt.Errorf("document cannot comply with PDF/A-2b: %s", strings.Join(issues, "; "))
+	}
+	return nil
+}
+
+// srgbProfile builds an ICC v2 display profile for sRGB IEC61966-2.1: the
+// D50 white point, the Bradford-adapted sRGB primaries and the sRGB tone
+// curve, as in the reference sRGB profiles
+func srgbProfile() []byte {
+	type tag struct {
+		sig  string
+		data []byte
+	}
+
+	xyz := func(x, y, z float64) []byte {
+		b := make([]byte, 20)
+		copy(b, "XYZ ")
+		for i, v := range []float64{x, y, z} {
+			binary.BigEndian.PutUint32(b[8+4*i:], uint32(int32(math.Round(v*65536))))
+		}
+		return b
+	}
+
+	desc := []byte("sRGB IEC61966-2.1")
+	descTag := make([]byte, 12+len(desc)+1+4+4+2+1+67)
+	copy(descTag, "desc")
+	binary.BigEndian.PutUint32(descTag[8:], uint32(len(desc)+1))
+	copy(descTag[12:], desc)
+
+	copyright := []byte("No copyright, use freely")
+	cprtTag := make([]byte, 8+len(copyright)+1)
+	copy(cprtTag, "text")
+	copy(cprtTag[8:], copyright)
+
+	// The sRGB tone curve of IEC 61966-2.1: linear near black, a 2.4
+	// power law above, sampled like the reference profiles do
+	const samples = 1024
+	curve := make([]byte, 12+2*samples)
+	copy(curve, "curv")
+	binary.BigEndian.PutUint32(curve[8:], samples)
+	for i := 0; i < samples; i++ {
+		v := float64(i) / (samples - 1)
+		if v <= 0.04045 {
+			v /= 12.92
+		} else {
+			v = math.Pow((v+0.055)/1.055, 2.4)
+		}
+		binary.BigEndian.PutUint16(curve[12+2*i:], uint16(math.Round(v*65535)))
+	}
+
+	tags := []tag{
+		{"desc", descTag},
+		{"cprt", cprtTag},
+		{"wtpt", xyz(0.9642, 1.0, 0.8249)},
+		{"rXYZ", xyz(0.4360747, 0.2225045, 0.0139322)},
+		{"gXYZ", xyz(0.3850649, 0.7168786, 0.0971045)},
+		{"bXYZ", xyz(0.1430804, 0.0606169, 0.7141733)},
+		{"rTRC", curve},
+		{"gTRC", curve},
+		{"bTRC", curve},
+	}
+
+	pad4 := func(n int) int { return (n + 3) &^ 3 }
+
+	tableSize := 4 + 12*len(tags)
+	offset := pad4(128 + tableSize)
+	var data bytes.Buffer
+	table := make([]byte, tableSize)
+	binary.BigEndian.PutUint32(table, uint32(len(tags)))
+	for i, t := range tags {
+		entry := table[4+12*i:]
+		copy(entry, t.sig)
+		binary.BigEndian.PutUint32(entry[4:], uint32(offset+data.Len()))
+		binary.BigEndian.PutUint32(entry[8:], uint32(len(t.data)))
+		data.Write(t.data)
+		data.Write(make([]byte, pad4(data.Len())-data.Len()))
+	}
+
+	size := offset + data.Len()
+	header := make([]byte, 128)
+	binary.BigEndian.PutUint32(header[0:], uint32(size))
+	binary.BigEndian.PutUint32(header[8:], 0x02100000) // version 2.1
+	copy(header[12:], "mntr")
+	copy(header[16:], "RGB ")
+	copy(header[20:], "XYZ ")
+	binary.BigEndian.PutUint16(header[24:], 2000) // creation date: 2000-01-01
+	binary.BigEndian.PutUint16(header[26:], 1)
+	binary.BigEndian.PutUint16(header[28:], 1)
+	copy(header[36:], "acsp")
+	copy(header[68:], xyz(0.9642, 1.0, 0.8249)[8:]) // PCS illuminant
+
+	profile := make([]byte, 0, size)
+	profile = append(profile, header...)
+	profile = append(profile, table...)
+	profile = append(profile, make([]byte, offset-128-tableSize)...)
+	profile = append(profile, data.Bytes()...)
+	return profile
+}
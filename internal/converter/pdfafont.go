@@ -0,0 +1,266 @@
+package converter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+var (
+	descendantRegex = regexp.MustCompile(`/DescendantFonts \[(\d+) 0 R\]`)
+	descriptorRegex = regexp.MustCompile(`/FontDescriptor (\d+) 0 R`)
+	cidToGIDRegex   = regexp.MustCompile(`/CIDToGIDMap (\d+) 0 R`)
+	fontFile2Regex  = regexp.MustCompile(`/FontFile2 (\d+) 0 R`)
+	length1Regex    = regexp.MustCompile(`/Length1 (\d+)`)
+)
+
+// type0Font is a Type0 font written by fpdf with the objects that hold its
+// font program and its CID to glyph mapping
+type type0Font struct {
+	name        string
+	fontFile    int // object numbers
+	cidToGIDMap int
+}
+
+// fontProgram returns the font file fpdf embeds under name, which is the
+// lower-cased family and the style registered by addFonts with a "utf8"
+// prefix, or nil for other fonts
+func fontProgram(name string) []byte {
+	switch name {
+	case "utf8dejavu":
+		return dejaVuSansFont
+	case "utf8dejavuB":
+		return dejaVuSansBoldFont
+	case "utf8dejavumono":
+		return dejaVuSansMonoFont
+	case "utf8dejavumonoB":
+		return dejaVuSansMonoBoldFont
+	}
+	return nil
+}
+
+// object returns the object with the given number
+func (f *pdfFile) object(num int) (*pdfObject, bool) {
+	for i := range f.objects {
+		if f.objects[i].num == num {
+			return &f.objects[i], true
+		}
+	}
+	return nil, false
+}
+
+// reference follows an indirect reference matched by re in the dictionary of obj
+func (f *pdfFile) reference(obj *pdfObject, re *regexp.Regexp) (*pdfObject, bool) {
+	m := re.FindSubmatch(obj.dictText())
+	if m == nil {
+		return nil, false
+	}
+	num, _ := strconv.Atoi(string(m[1]))
+	return f.object(num)
+}
+
+// type0Fonts returns the Type0 fonts of a document. Fonts whose objects
+// cannot be followed are left out.
+func type0Fonts(file *pdfFile) []type0Font {
+	var fonts []type0Font
+	for i := range file.objects {
+		obj := &file.objects[i]
+		dict := obj.dictText()
+		if !bytes.Contains(dict, []byte("/Subtype /Type0")) {
+			continue
+		}
+		name := fontNameRegex.FindSubmatch(dict)
+		cidFont, ok := file.reference(obj, descendantRegex)
+		if name == nil || !ok {
+			continue
+		}
+		descriptor, ok := file.reference(cidFont, descriptorRegex)
+		if !ok {
+			continue
+		}
+		fontFile, ok := file.reference(descriptor, fontFile2Regex)
+		if !ok {
+			continue
+		}
+		cidToGIDMap, ok := file.reference(cidFont, cidToGIDRegex)
+		if !ok {
+			continue
+		}
+		fonts = append(fonts, type0Font{name: string(name[2]), fontFile: fontFile.num, cidToGIDMap: cidToGIDMap.num})
+	}
+	return fonts
+}
+
+// embedFullFonts replaces the font subsets fpdf embeds with the complete
+// font programs. fpdf uses the Unicode code points as CIDs and renumbers
+// the glyphs of the subset, so the CID to glyph mapping is rebuilt from the
+// cmap of the full font.
+func embedFullFonts(file *pdfFile) error {
+	for _, font := range type0Fonts(file) {
+		program := fontProgram(font.name)
+		if program == nil {
+			return fmt.Errorf("font %s cannot be embedded in full", font.name)
+		}
+		glyphs, err := fontGlyphs(program)
+		if err != nil {
+			return fmt.Errorf("failed to read font %s: %w", font.name, err)
+		}
+
+		// Identity-H limits CIDs to two bytes
+		cidToGID := make([]byte, 2*0x10000)
+		for code, glyph := range glyphs {
+			if code <= 0xFFFF {
+				binary.BigEndian.PutUint16(cidToGID[2*code:], glyph)
+			}
+		}
+
+		fontFile, _ := file.object(font.fontFile)
+		fontFile.body = flateStream(fmt.Sprintf("/Length1 %d", len(program)), program)
+		cidToGIDMap, _ := file.object(font.cidToGIDMap)
+		cidToGIDMap.body = flateStream("", cidToGID)
+	}
+	return nil
+}
+
+// flateStream returns the body of a Flate-compressed stream object with
+// the given extra dictionary entries
+func flateStream(entries string, data []byte) []byte {
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, _ = zw.Write(data)
+	_ = zw.Close()
+
+	var body bytes.Buffer
+	fmt.Fprintf(&body, "<< %s /Filter /FlateDecode /Length %d >>\nstream\n", entries, compressed.Len())
+	body.Write(compressed.Bytes())
+	body.WriteString("\nendstream\n")
+	return body.Bytes()
+}
+
+// fontGlyphs returns the glyph of every character in the Unicode cmap
+// subtable of a TrueType font, preferring the full-repertoire format 12
+// table over the BMP-only format 4 table
+func fontGlyphs(program []byte) (map[rune]uint16, error) {
+	cmap, ok := trueTypeTable(program, "cmap")
+	if !ok || len(cmap) < 4 {
+		return nil, errors.New("font has no cmap table")
+	}
+
+	var format4, format12 []byte
+	count := int(binary.BigEndian.Uint16(cmap[2:]))
+	for i := 0; i < count && 4+8*i+8 <= len(cmap); i++ {
+		record := cmap[4+8*i:]
+		platform := binary.BigEndian.Uint16(record)
+		encoding := binary.BigEndian.Uint16(record[2:])
+		offset := int(binary.BigEndian.Uint32(record[4:]))
+		if offset+2 > len(cmap) {
+			continue
+		}
+		unicode := platform == 0 || (platform == 3 && (encoding == 1 || encoding == 10))
+		switch format := binary.BigEndian.Uint16(cmap[offset:]); {
+		case unicode && format == 4 && format4 == nil:
+			format4 = cmap[offset:]
+		case unicode && format == 12 && format12 == nil:
+			format12 = cmap[offset:]
+		}
+	}
+
+	switch {
+	case format12 != nil:
+		return cmapFormat12(format12)
+	case format4 != nil:
+		return cmapFormat4(format4)
+	}
+	return nil, errors.New("font has no Unicode cmap subtable")
+}
+
+// trueTypeTable returns a table of a TrueType font by its tag
+func trueTypeTable(program []byte, tag string) ([]byte, bool) {
+	if len(program) < 12 {
+		return nil, false
+	}
+	count := int(binary.BigEndian.Uint16(program[4:]))
+	for i := 0; i < count && 12+16*i+16 <= len(program); i++ {
+		record := program[12+16*i:]
+		if string(record[:4]) != tag {
+			continue
+		}
+		offset := int(binary.BigEndian.Uint32(record[8:]))
+		length := int(binary.BigEndian.Uint32(record[12:]))
+		if offset+length > len(program) {
+			return nil, false
+		}
+		return program[offset : offset+length], true
+	}
+	return nil, false
+}
+
+// cmapFormat4 reads a segment mapping to delta values subtable
+func cmapFormat4(table []byte) (map[rune]uint16, error) {
+	if len(table) < 14 {
+		return nil, errors.New("truncated cmap subtable")
+	}
+	segments := int(binary.BigEndian.Uint16(table[6:])) / 2
+	ends := 14
+	starts := ends + 2*segments + 2
+	deltas := starts + 2*segments
+	rangeOffsets := deltas + 2*segments
+	if rangeOffsets+2*segments > len(table) {
+		return nil, errors.New("truncated cmap subtable")
+	}
+
+	glyphs := make(map[rune]uint16)
+	for s := 0; s < segments; s++ {
+		end := int(binary.BigEndian.Uint16(table[ends+2*s:]))
+		start := int(binary.BigEndian.Uint16(table[starts+2*s:]))
+		delta := binary.BigEndian.Uint16(table[deltas+2*s:])
+		rangeOffset := int(binary.BigEndian.Uint16(table[rangeOffsets+2*s:]))
+		for c := start; c <= end && c != 0xFFFF; c++ {
+			glyph := uint16(c) + delta
+			if rangeOffset != 0 {
+				// The offset is relative to its own position in the table
+				at := rangeOffsets + 2*s + rangeOffset + 2*(c-start)
+				if at+2 > len(table) {
+					break
+				}
+				if glyph = binary.BigEndian.Uint16(table[at:]); glyph != 0 {
+					glyph += delta
+				}
+			}
+			if glyph != 0 {
+				glyphs[rune(c)] = glyph
+			}
+		}
+	}
+	return glyphs, nil
+}
+
+// cmapFormat12 reads a segmented coverage subtable
+func cmapFormat12(table []byte) (map[rune]uint16, error) {
+	if len(table) < 16 {
+		return nil, errors.New("truncated cmap subtable")
+	}
+	groups := int(binary.BigEndian.Uint32(table[12:]))
+	if 16+12*groups > len(table) {
+		return nil, errors.New("truncated cmap subtable")
+	}
+
+	glyphs := make(map[rune]uint16)
+	for g := 0; g < groups; g++ {
+		group := table[16+12*g:]
+		start := binary.BigEndian.Uint32(group)
+		end := binary.BigEndian.Uint32(group[4:])
+		glyph := binary.BigEndian.Uint32(group[8:])
+		if end < start || end > 0x10FFFF {
+			continue
+		}
+		for c := start; c <= end; c++ {
+			glyphs[rune(c)] = uint16(glyph + c - start)
+		}
+	}
+	return glyphs, nil
+}
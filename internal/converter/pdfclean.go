@@ -0,0 +1,155 @@
+package converter
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+// Running header and footer heuristics
+const (
+	edgeLines         = 3   // lines at the top and bottom of a page that may be headers or footers
+	positionTolerance = 2.0 // points, vertical distance that still counts as the same position
+	minRepeatRatio    = 0.5 // share of pages a header or footer must appear on
+)
+
+var (
+	digitsRegex = regexp.MustCompile(`\d+`)
+	// pageNumberRegex matches lines such as "7", "- 7 -", "Page 7", "7 of 40" or "xii"
+	pageNumberRegex = regexp.MustCompile(`(?i)^[-–—\s]*(page\s+)?(\d+|x{1,3}(ix|iv|v?i{0,3})|ix|iv|v?i{1,3}|v)(\s*(of|/)\s*\d+)?[-–—\s]*$`)
+	// pageTokenRegex matches a page number next to the text of a running
+	// header or footer, as in "Chapter 2 - page 14", "Annual Report | 14"
+	// or "14 · Annual Report"
+	pageTokenRegex = regexp.MustCompile(`(?i)(^|\s)(page|p\.|pg\.?)\s*\d+|\d+\s*(of|/)\s*\d+|^\d+\s*[-–—|·•:]|[-–—|·•:]\s*\d+$`)
+)
+
+// runningKey identifies a line by its text and vertical position
+type runningKey struct {
+	text string
+	y    float64
+}
+
+// runningLineKey returns the key under which a line is compared with the
+// lines of other pages. Numbers are ignored in lines that carry a page
+// number, so "Chapter 2 - page 14" matches on every page, and page numbers
+// such as "7" or "xii" all match each other. Other lines must repeat
+// exactly, so numbered headings such as "Chapter 1" and "Chapter 2" differ.
+func runningLineKey(line textLine) runningKey {
+	text := strings.ToLower(strings.Join(strings.Fields(line.Text()), " "))
+	switch {
+	case pageNumberRegex.MatchString(text):
+		text = "page #"
+	case pageTokenRegex.MatchString(text):
+		text = digitsRegex.ReplaceAllString(text, "#")
+	}
+	return runningKey{text, math.Round(line.Y / positionTolerance)}
+}
+
+// runningLines finds the headers, footers and page numbers that repeat at
+// the same vertical position on many pages. The keys of the edge lines of
+// every page are counted first; lines are then removed page by page.
+type runningLines struct {
+	counts map[runningKey]int
+	pages  int
+}
+
+func newRunningLines() *runningLines {
+	return &runningLines{counts: make(map[runningKey]int)}
+}
+
+// edgeKeys returns the distinct keys of the edge lines of a page
+func edgeKeys(page pageLayout) []runningKey {
+	var keys []runningKey
+	seen := make(map[runningKey]bool)
+	for _, i := range edgeLineIndexes(page.Lines) {
+		if k := runningLineKey(page.Lines[i]); !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// add counts the edge line keys of a page
+func (r *runningLines) add(keys []runningKey) {
+	r.pages++
+	for _, k := range keys {
+		r.counts[k]++
+	}
+}
+
+// repeated reports whether an edge line key recurs on enough pages to be a
+// running line. A lone number that does not recur, e.g. on a one-page
+// document, is kept as content.
+func (r *runningLines) repeated(k runningKey) bool {
+	n := r.counts[k]
+	return r.pages > 1 && n >= 2 && float64(n) >= float64(r.pages)*minRepeatRatio
+}
+
+// remove drops the running lines of a page and returns how many were removed
+func (r *runningLines) remove(page *pageLayout) int {
+	drop := make(map[int]bool)
+	for _, i := range edgeLineIndexes(page.Lines) {
+		if r.repeated(runningLineKey(page.Lines[i])) {
+			drop[i] = true
+		}
+	}
+	if len(drop) == 0 {
+		return 0
+	}
+
+	kept := page.Lines[:0]
+	for i, line := range page.Lines {
+		if !drop[i] {
+			kept = append(kept, line)
+		}
+	}
+	page.Lines = kept
+	return len(drop)
+}
+
+// removeRunningLines drops the running headers, footers and page numbers
+// of a document held in memory and returns the number of lines removed
+func removeRunningLines(pages []pageLayout) int {
+	running := newRunningLines()
+	for _, page := range pages {
+		running.add(edgeKeys(page))
+	}
+	removed := 0
+	for p := range pages {
+		removed += running.remove(&pages[p])
+	}
+	return removed
+}
+
+// edgeLineIndexes returns the indexes of the topmost and bottommost text
+// lines of a page. Images are neither edge lines nor counted, so the edge
+// lines are the same whether or not images are extracted.
+func edgeLineIndexes(lines []textLine) []int {
+	var text []int
+	for i, line := range lines {
+		if line.Image == "" {
+			text = append(text, i)
+		}
+	}
+	if len(text) <= 2*edgeLines {
+		return text
+	}
+
+	// Lines are in reading order, which is not necessarily top to bottom
+	var indexes []int
+	for _, i := range text {
+		above, below := 0, 0
+		for _, j := range text {
+			if lines[j].Y > lines[i].Y {
+				above++
+			} else if lines[j].Y < lines[i].Y {
+				below++
+			}
+		}
+		if above < edgeLines || below < edgeLines {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
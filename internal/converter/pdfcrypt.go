@@ -0,0 +1,80 @@
+package converter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Errors returned by pdf2md when a PDF cannot be opened. Callers can tell
+// them apart with errors.Is.
+var (
+	// ErrPasswordRequired reports an encrypted PDF converted without a password
+	ErrPasswordRequired = errors.New("PDF is encrypted, password required")
+	// ErrWrongPassword reports an encrypted PDF whose password did not match
+	ErrWrongPassword = errors.New("wrong PDF password")
+	// ErrUnsupportedEncryption reports an encryption method that cannot be decrypted
+	ErrUnsupportedEncryption = errors.New("unsupported PDF encryption")
+	// ErrCorruptPDF reports input that is not a readable PDF file
+	ErrCorruptPDF = errors.New("corrupt PDF")
+)
+
+// openPDF opens the PDF of size bytes read from src, decrypting it with
+// password if it is encrypted. PDFs encrypted with an empty user password
+// open without one.
+func openPDF(src io.ReaderAt, size int64, password string) (reader *pdf.Reader, err error) {
+	// The parser panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			reader, err = nil, fmt.Errorf("%w: %v", ErrCorruptPDF, r)
+		}
+	}()
+
+	tried := false
+	reader, err = pdf.NewReaderEncrypted(src, size, func() string {
+		if tried {
+			return ""
+		}
+		tried = true
+		return password
+	})
+	switch {
+	case err == nil:
+		if err := checkKeyLength(reader); err != nil {
+			return nil, err
+		}
+		return reader, nil
+	case errors.Is(err, pdf.ErrInvalidPassword) && password == "":
+		return nil, ErrPasswordRequired
+	case errors.Is(err, pdf.ErrInvalidPassword):
+		return nil, ErrWrongPassword
+	case strings.HasPrefix(err.Error(), "unsupported PDF: encryption"):
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedEncryption, err)
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrCorruptPDF, err)
+	}
+}
+
+// minKeyLength is the shortest RC4 key, in bits, that decrypts correctly.
+// The reader derives object keys from the full MD5 hash instead of the
+// first n+5 bytes, which only gives the same key for keys of 88 bits or more.
+const minKeyLength = 88
+
+// checkKeyLength rejects encrypted files whose key is too short to decrypt
+func checkKeyLength(reader *pdf.Reader) error {
+	encrypt := reader.Trailer().Key("Encrypt")
+	if encrypt.IsNull() {
+		return nil
+	}
+	bits := encrypt.Key("Length").Int64()
+	if bits == 0 {
+		bits = 40
+	}
+	if encrypt.Key("V").Int64() < 4 && bits < minKeyLength {
+		return fmt.Errorf("%w: %d-bit RC4 keys are not supported", ErrUnsupportedEncryption, bits)
+	}
+	return nil
+}
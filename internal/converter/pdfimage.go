@@ -0,0 +1,552 @@
+package converter
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Image extraction limits
+const (
+	minImageSize  = 4.0 // points, smaller images (bullets, rules) are ignored
+	maxFormDepth  = 8   // nesting of form XObjects that is followed
+	maxImagePixel = 1 << 26
+)
+
+// streamOffsetRegex extracts the file offset from the string form of a
+// stream value, "<<dict>>@offset". The reader has no other way to reach the
+// raw data of a stream, and this debugging format is not documented: it is
+// pinned to github.com/ledongthuc/pdf v0.0.0-20220302134840-0c2507a12d80
+// (Value.String in read.go). Without the suffix, images fall back to the
+// data decoded by Value.Reader or are skipped.
+var streamOffsetRegex = regexp.MustCompile(`@(\d+)$`)
+
+// streamString returns the string form of a stream value, replaceable in tests
+var streamString = pdf.Value.String
+
+// errNoStreamOffset reports a stream whose raw data cannot be located
+var errNoStreamOffset = fmt.Errorf("%w: stream offset unknown", errUnsupportedImage)
+
+// matrix is a PDF transformation matrix [a b c d e f]
+type matrix [6]float64
+
+var identity = matrix{1, 0, 0, 1, 0, 0}
+
+// multiply returns m × n, i.e. m applied first
+func (m matrix) multiply(n matrix) matrix {
+	return matrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// imageExtractor saves the images of a PDF and returns their placement on each page
+type imageExtractor struct {
+	src       io.ReaderAt // the PDF file, for copying JPEG data unchanged
+	size      int64
+	dir       string // directory the images are written to
+	prefix    string // path of dir as referenced from the Markdown
+	name      string // start of the file names, so documents sharing dir keep their images apart
+	encrypted bool   // raw stream data is encrypted and cannot be copied
+
+	saved   map[string]string // file names by stream, so repeated images are saved once
+	skipped int               // images in unsupported formats
+}
+
+// newImageExtractor returns an extractor writing into dir, referenced as
+// prefix. File names start with name if it is not empty.
+func newImageExtractor(src io.ReaderAt, size int64, reader *pdf.Reader, dir, prefix, name string) *imageExtractor {
+	return &imageExtractor{
+		src:       src,
+		size:      size,
+		dir:       dir,
+		prefix:    prefix,
+		name:      name,
+		encrypted: !reader.Trailer().Key("Encrypt").IsNull(),
+		saved:     make(map[string]string),
+	}
+}
+
+// imagePlacement is an image XObject and the area of the page it is drawn on
+type imagePlacement struct {
+	xobj           pdf.Value
+	x0, y0, x1, y1 float64
+}
+
+// pageImages are the images found on a page
+type pageImages struct {
+	placements []imagePlacement
+	skipped    int // images that could not be read
+}
+
+// findImages returns the images drawn on a page. It may be called
+// concurrently for different pages.
+func (e *imageExtractor) findImages(page pdf.Page) (found pageImages) {
+	// The content stream parser panics on malformed input
+	defer func() {
+		if r := recover(); r != nil {
+			found.skipped++
+		}
+	}()
+
+	e.walk(page.V.Key("Contents"), page.Resources(), identity, 0, func(xobj pdf.Value, ctm matrix) {
+		x0, x1 := math.Min(ctm[4], ctm[4]+ctm[0]+ctm[2]), math.Max(ctm[4], ctm[4]+ctm[0]+ctm[2])
+		y0, y1 := math.Min(ctm[5], ctm[5]+ctm[1]+ctm[3]), math.Max(ctm[5], ctm[5]+ctm[1]+ctm[3])
+		if x1-x0 >= minImageSize && y1-y0 >= minImageSize {
+			found.placements = append(found.placements, imagePlacement{xobj, x0, y0, x1, y1})
+		}
+	})
+	return found
+}
+
+// saveImages saves the images found on a page that were not saved before
+// and returns them as layout lines positioned where they are drawn, along
+// with the number of images skipped. Only failures to write an image are
+// returned as errors. Pages must be saved one at a time and in order, which
+// keeps the file names independent of scheduling.
+func (e *imageExtractor) saveImages(pageNum int, found pageImages) (images []textLine, skipped int, err error) {
+	skipped = found.skipped
+	count := 0
+	for _, p := range found.placements {
+		key := p.xobj.String()
+		name, ok := e.saved[key]
+		if !ok {
+			count++
+			var saveErr error
+			base := fmt.Sprintf("page%d-img%d", pageNum, count)
+			if e.name != "" {
+				base = e.name + "-" + base
+			}
+			name, saveErr = e.trySave(p.xobj, base)
+			if saveErr != nil {
+				if err == nil && !errors.Is(saveErr, errUnsupportedImage) {
+					err = saveErr
+				}
+				skipped++
+				continue
+			}
+			e.saved[key] = name
+		}
+
+		images = append(images, textLine{
+			Image:  path.Join(e.prefix, name),
+			X:      p.x0,
+			Right:  p.x1,
+			Y:      p.y0,
+			Height: p.y1 - p.y0,
+		})
+	}
+	e.skipped += skipped
+	return images, skipped, err
+}
+
+// trySave saves an image, treating panics of the PDF library on malformed
+// image data as an unsupported image
+func (e *imageExtractor) trySave(xobj pdf.Value, base string) (name string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			name, err = "", errUnsupportedImage
+		}
+	}()
+	return e.save(xobj, base)
+}
+
+// walk interprets content streams, following form XObjects, and calls found
+// for every image with the transformation that maps it onto the page
+func (e *imageExtractor) walk(contents, resources pdf.Value, ctm matrix, depth int, found func(pdf.Value, matrix)) {
+	streams := []pdf.Value{contents}
+	if contents.Kind() == pdf.Array {
+		streams = streams[:0]
+		for i := 0; i < contents.Len(); i++ {
+			streams = append(streams, contents.Index(i))
+		}
+	}
+
+	var saved []matrix
+	for _, strm := range streams {
+		if strm.Kind() != pdf.Stream {
+			continue
+		}
+		pdf.Interpret(strm, func(stk *pdf.Stack, op string) {
+			n := stk.Len()
+			args := make([]pdf.Value, n)
+			for i := n - 1; i >= 0; i-- {
+				args[i] = stk.Pop()
+			}
+
+			switch op {
+			case "q":
+				saved = append(saved, ctm)
+			case "Q":
+				if len(saved) > 0 {
+					ctm = saved[len(saved)-1]
+					saved = saved[:len(saved)-1]
+				}
+			case "cm":
+				if len(args) == 6 {
+					var m matrix
+					for i := range m {
+						m[i] = args[i].Float64()
+					}
+					ctm = m.multiply(ctm)
+				}
+			case "Do":
+				if len(args) != 1 {
+					return
+				}
+				xobj := resources.Key("XObject").Key(args[0].Name())
+				switch xobj.Key("Subtype").Name() {
+				case "Image":
+					if !xobj.Key("ImageMask").Bool() {
+						found(xobj, ctm)
+					}
+				case "Form":
+					if depth < maxFormDepth {
+						form := ctm
+						if m := xobj.Key("Matrix"); m.Len() == 6 {
+							var fm matrix
+							for i := range fm {
+								fm[i] = m.Index(i).Float64()
+							}
+							form = fm.multiply(ctm)
+						}
+						formResources := xobj.Key("Resources")
+						if formResources.IsNull() {
+							formResources = resources
+						}
+						e.walk(xobj, formResources, form, depth+1, found)
+					}
+				}
+			}
+		})
+	}
+}
+
+// errUnsupportedImage reports an image whose encoding cannot be converted
+var errUnsupportedImage = errors.New("unsupported image format")
+
+// save writes an image XObject to the assets directory and returns its file name.
+// JPEG and JPEG 2000 data is copied unchanged; other images are re-encoded as PNG.
+func (e *imageExtractor) save(xobj pdf.Value, base string) (string, error) {
+	var filters []string
+	switch f := xobj.Key("Filter"); f.Kind() {
+	case pdf.Name:
+		filters = []string{f.Name()}
+	case pdf.Array:
+		for i := 0; i < f.Len(); i++ {
+			filters = append(filters, f.Index(i).Name())
+		}
+	}
+
+	var name string
+	var data []byte
+	switch {
+	case len(filters) == 1 && (filters[0] == "DCTDecode" || filters[0] == "JPXDecode"):
+		raw, err := e.rawStream(xobj)
+		if err != nil {
+			return "", err
+		}
+		ext := ".jpg"
+		if filters[0] == "JPXDecode" {
+			ext = ".jp2"
+		}
+		name, data = base+ext, raw
+
+	case len(filters) == 0 || (len(filters) == 1 && filters[0] == "FlateDecode"):
+		samples, err := e.imageSamples(xobj, len(filters) == 1)
+		if err != nil {
+			return "", err
+		}
+		img, err := decodeImage(xobj, samples)
+		if err != nil {
+			return "", err
+		}
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, img); err != nil {
+			return "", fmt.Errorf("failed to encode image: %w", err)
+		}
+		name, data = base+".png", buf.Bytes()
+
+	default:
+		return "", errUnsupportedImage
+	}
+
+	if err := os.MkdirAll(e.dir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create assets directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(e.dir, name), data, 0o644); err != nil {
+		return "", fmt.Errorf("failed to write image: %w", err)
+	}
+	return name, nil
+}
+
+// rawStream returns the undecoded data of a stream. The reader only exposes
+// decoded data, but the string form of a stream value carries its file offset.
+func (e *imageExtractor) rawStream(strm pdf.Value) ([]byte, error) {
+	if e.encrypted {
+		return nil, errUnsupportedImage
+	}
+	m := streamOffsetRegex.FindStringSubmatch(streamString(strm))
+	if m == nil {
+		return nil, errNoStreamOffset
+	}
+	offset, _ := strconv.ParseInt(m[1], 10, 64)
+	length := strm.Key("Length").Int64()
+	if offset < 0 || length <= 0 || offset+length > e.size {
+		return nil, errUnsupportedImage
+	}
+	data := make([]byte, length)
+	if _, err := e.src.ReadAt(data, offset); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	return data, nil
+}
+
+// imageSamples returns the decoded samples of an uncompressed or Flate
+// encoded image. Flate data is inflated here rather than by the reader, which
+// does not support the PNG predictors used by most PDF writers.
+func (e *imageExtractor) imageSamples(xobj pdf.Value, flate bool) (samples []byte, err error) {
+	// Corrupt data makes the stream reader panic
+	defer func() {
+		if r := recover(); r != nil {
+			samples, err = nil, errUnsupportedImage
+		}
+	}()
+
+	params := xobj.Key("DecodeParms")
+	if params.Kind() == pdf.Array {
+		params = params.Index(0)
+	}
+	predictor := params.Key("Predictor").Int64()
+
+	raw, err := e.rawStream(xobj)
+	if e.encrypted || errors.Is(err, errNoStreamOffset) {
+		// Only the reader can decrypt or locate the data, and it supports
+		// no predictors but 12
+		if predictor > 1 && predictor != 12 {
+			return nil, errUnsupportedImage
+		}
+		rd := xobj.Reader()
+		defer rd.Close()
+		return io.ReadAll(rd)
+	}
+	if err != nil || !flate {
+		return raw, err
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return nil, errUnsupportedImage
+	}
+	data, err := io.ReadAll(zr)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil, errUnsupportedImage
+	}
+
+	switch {
+	case predictor <= 1:
+		return data, nil
+	case predictor >= 10:
+		colors := max(1, int(params.Key("Colors").Int64()))
+		bpc := int(params.Key("BitsPerComponent").Int64())
+		if bpc == 0 {
+			bpc = 8
+		}
+		columns := max(1, int(params.Key("Columns").Int64()))
+		return unpredictPNG(data, colors, bpc, columns)
+	default:
+		return nil, errUnsupportedImage
+	}
+}
+
+// unpredictPNG reverses the PNG row filters applied before Flate compression
+func unpredictPNG(data []byte, colors, bpc, columns int) ([]byte, error) {
+	bpp := max(1, colors*bpc/8)
+	rowLen := (colors*bpc*columns + 7) / 8
+	if rowLen <= 0 || len(data)%(rowLen+1) != 0 {
+		return nil, errUnsupportedImage
+	}
+
+	out := make([]byte, 0, len(data)/(rowLen+1)*rowLen)
+	prev := make([]byte, rowLen)
+	for len(data) > 0 {
+		filter, row := data[0], data[1:rowLen+1]
+		data = data[rowLen+1:]
+		for i := range row {
+			var left, upLeft byte
+			if i >= bpp {
+				left, upLeft = row[i-bpp], prev[i-bpp]
+			}
+			up := prev[i]
+			switch filter {
+			case 0:
+			case 1:
+				row[i] += left
+			case 2:
+				row[i] += up
+			case 3:
+				row[i] += byte((int(left) + int(up)) / 2)
+			case 4:
+				row[i] += paeth(left, up, upLeft)
+			default:
+				return nil, errUnsupportedImage
+			}
+		}
+		out = append(out, row...)
+		prev = row
+	}
+	return out, nil
+}
+
+// paeth is the Paeth predictor of the PNG specification
+func paeth(a, b, c byte) byte {
+	p := int(a) + int(b) - int(c)
+	pa, pb, pc := abs(p-int(a)), abs(p-int(b)), abs(p-int(c))
+	switch {
+	case pa <= pb && pa <= pc:
+		return a
+	case pb <= pc:
+		return b
+	default:
+		return c
+	}
+}
+
+// abs returns the absolute value of x
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+// decodeImage converts image samples to an image
+func decodeImage(xobj pdf.Value, samples []byte) (image.Image, error) {
+	width := int(xobj.Key("Width").Int64())
+	height := int(xobj.Key("Height").Int64())
+	bpc := int(xobj.Key("BitsPerComponent").Int64())
+	if width <= 0 || height <= 0 || width*height > maxImagePixel {
+		return nil, errUnsupportedImage
+	}
+	if bpc != 1 && bpc != 2 && bpc != 4 && bpc != 8 {
+		return nil, errUnsupportedImage
+	}
+
+	space, palette := colorSpace(xobj.Key("ColorSpace"))
+	components := map[string]int{"DeviceGray": 1, "DeviceRGB": 3, "DeviceCMYK": 4, "Indexed": 1}[space]
+	if components == 0 || (bpc != 8 && space != "DeviceGray" && space != "Indexed") {
+		return nil, errUnsupportedImage
+	}
+
+	stride := (width*components*bpc + 7) / 8
+	if len(samples) < stride*height {
+		return nil, errUnsupportedImage
+	}
+
+	sample := func(row []byte, i int) int {
+		switch bpc {
+		case 8:
+			return int(row[i])
+		default:
+			bit := i * bpc
+			return int(row[bit/8]>>(8-bpc-bit%8)) & (1<<bpc - 1)
+		}
+	}
+
+	rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		row := samples[y*stride : (y+1)*stride]
+		for x := 0; x < width; x++ {
+			var c color.RGBA
+			switch space {
+			case "DeviceGray":
+				v := uint8(sample(row, x) * 255 / (1<<bpc - 1))
+				c = color.RGBA{v, v, v, 255}
+			case "DeviceRGB":
+				c = color.RGBA{row[x*3], row[x*3+1], row[x*3+2], 255}
+			case "DeviceCMYK":
+				k := 255 - int(row[x*4+3])
+				c = color.RGBA{
+					uint8((255 - int(row[x*4])) * k / 255),
+					uint8((255 - int(row[x*4+1])) * k / 255),
+					uint8((255 - int(row[x*4+2])) * k / 255),
+					255,
+				}
+			case "Indexed":
+				i := sample(row, x)
+				if i >= len(palette) {
+					return nil, errUnsupportedImage
+				}
+				c = palette[i]
+			}
+			rgba.SetRGBA(x, y, c)
+		}
+	}
+	return rgba, nil
+}
+
+// colorSpace resolves an image color space to a device space, returning the
+// palette of indexed images. ICC based spaces use the device space of the
+// same number of components.
+func colorSpace(cs pdf.Value) (string, []color.RGBA) {
+	switch cs.Kind() {
+	case pdf.Name:
+		return cs.Name(), nil
+	case pdf.Array:
+	default:
+		return "", nil
+	}
+
+	switch cs.Index(0).Name() {
+	case "ICCBased":
+		switch cs.Index(1).Key("N").Int64() {
+		case 1:
+			return "DeviceGray", nil
+		case 3:
+			return "DeviceRGB", nil
+		case 4:
+			return "DeviceCMYK", nil
+		}
+	case "Indexed":
+		base, _ := colorSpace(cs.Index(1))
+		hival := int(cs.Index(2).Int64())
+		lookup := cs.Index(3)
+		table := []byte(lookup.RawString())
+		if lookup.Kind() == pdf.Stream {
+			rd := lookup.Reader()
+			table, _ = io.ReadAll(rd)
+			rd.Close()
+		}
+
+		n := map[string]int{"DeviceGray": 1, "DeviceRGB": 3}[base]
+		if n == 0 || len(table) < (hival+1)*n {
+			return "", nil
+		}
+		palette := make([]color.RGBA, hival+1)
+		for i := range palette {
+			if n == 1 {
+				palette[i] = color.RGBA{table[i], table[i], table[i], 255}
+			} else {
+				palette[i] = color.RGBA{table[i*3], table[i*3+1], table[i*3+2], 255}
+			}
+		}
+		return "Indexed", palette
+	}
+	return "", nil
+}
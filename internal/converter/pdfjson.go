@@ -0,0 +1,144 @@
+package converter
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+)
+
+// Output formats of the "format" option
+const (
+	formatMarkdown = "markdown"
+	formatJSON     = "json"
+)
+
+// jsonDocument is the positional extraction data written by the JSON format.
+// Coordinates are in points, measured from the bottom left of the page as in
+// PDF user space; y is the baseline of text and the bottom edge of images.
+type jsonDocument struct {
+	Metadata map[string]string `json:"metadata"`
+	Pages    []jsonPage        `json:"pages"`
+	Fields   []jsonField       `json:"form_fields,omitempty"`
+}
+
+type jsonPage struct {
+	Number int         `json:"number"`
+	Width  float64     `json:"width"`
+	Height float64     `json:"height"`
+	Blocks []jsonBlock `json:"blocks"`
+}
+
+// jsonBlock is a block as classified by the Markdown heuristics
+type jsonBlock struct {
+	Type   string     `json:"type"` // heading, paragraph, list_item, code, table or image
+	Level  int        `json:"level,omitempty"`
+	Marker string     `json:"marker,omitempty"`
+	Depth  int        `json:"depth,omitempty"`
+	Image  string     `json:"image,omitempty"`
+	Cells  [][]string `json:"cells,omitempty"`
+	Lines  []jsonLine `json:"lines"`
+}
+
+type jsonLine struct {
+	Text   string    `json:"text"`
+	X      float64   `json:"x"`
+	Y      float64   `json:"y"`
+	Width  float64   `json:"width"`
+	Height float64   `json:"height,omitempty"`
+	Size   float64   `json:"size,omitempty"`
+	Runs   []jsonRun `json:"runs,omitempty"`
+}
+
+type jsonRun struct {
+	Text   string  `json:"text"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Font   string  `json:"font"`
+	Size   float64 `json:"size"`
+	Bold   bool    `json:"bold,omitempty"`
+	Italic bool    `json:"italic,omitempty"`
+	Mono   bool    `json:"mono,omitempty"`
+	Link   string  `json:"link,omitempty"`
+}
+
+type jsonField struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// writeJSON writes the pages, their blocks, lines and runs as indented JSON
+func writeJSON(w io.Writer, pages []pageLayout, blocks [][]pdfBlock, fields []formField, metadata map[string]string) error {
+	doc := jsonDocument{Metadata: metadata, Pages: make([]jsonPage, len(pages))}
+	for i, page := range pages {
+		doc.Pages[i] = jsonPage{
+			Number: page.Number,
+			Width:  round2(page.Width),
+			Height: round2(page.Height),
+			Blocks: make([]jsonBlock, len(blocks[i])),
+		}
+		for j, block := range blocks[i] {
+			doc.Pages[i].Blocks[j] = jsonBlockOf(block)
+		}
+	}
+	for _, field := range fields {
+		doc.Fields = append(doc.Fields, jsonField(field))
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+// jsonBlockOf converts a block with its lines and runs
+func jsonBlockOf(block pdfBlock) jsonBlock {
+	out := jsonBlock{Level: block.Level, Image: block.Image, Cells: block.Table, Lines: make([]jsonLine, len(block.Lines))}
+	switch {
+	case block.Image != "":
+		out.Type = "image"
+	case block.Table != nil:
+		out.Type = "table"
+	case block.Code:
+		out.Type = "code"
+	case block.Level > 0:
+		out.Type = "heading"
+	case block.Item != "":
+		out.Type = "list_item"
+		out.Marker, out.Depth = block.Item, block.Depth
+	default:
+		out.Type = "paragraph"
+	}
+
+	for i, line := range block.Lines {
+		jl := jsonLine{
+			Text:   line.Text(),
+			X:      round2(line.X),
+			Y:      round2(line.Y),
+			Width:  round2(line.Right - line.X),
+			Height: round2(line.Height),
+			Size:   round2(line.Size),
+		}
+		for _, run := range line.Runs {
+			style := styleOf(run.Font)
+			jl.Runs = append(jl.Runs, jsonRun{
+				Text:   run.Text,
+				X:      round2(run.X),
+				Y:      round2(line.Y),
+				Width:  round2(run.Right - run.X),
+				Font:   run.Font,
+				Size:   round2(run.Size),
+				Bold:   style.Bold,
+				Italic: style.Italic,
+				Mono:   style.Mono,
+				Link:   run.Link,
+			})
+		}
+		out.Lines[i] = jl
+	}
+	return out
+}
+
+// round2 rounds a coordinate to hundredths of a point
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,364 @@
+package converter
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Layout heuristics, relative to the font size of the text involved
+const (
+	sameLineTolerance = 0.4  // baseline shift that still counts as the same line
+	wordGapRatio      = 0.25 // horizontal gap that separates two words
+	paragraphGapRatio = 1.75 // baseline distance that starts a new paragraph
+	glyphWidthRatio   = 0.5  // estimated glyph width when the PDF reports none
+	headingSizeRatio  = 1.1  // minimum size of a heading relative to body text
+	maxHeadingLength  = 200  // longer lines are never headings
+	maxHeadingLevels  = 3
+)
+
+// textRun is a sequence of adjacent glyphs on one line sharing font and size
+type textRun struct {
+	Text  string
+	Font  string
+	Size  float64
+	X     float64
+	Right float64 // end of the last glyph, estimated if the PDF reports no widths
+	Link  string  // URL of the link annotation covering the run
+}
+
+// textLine is a line of text on a page. Y is the baseline, measured from
+// the bottom of the page as in PDF user space. Images take part in the
+// layout as lines without text: Image is their Markdown path and Y their
+// bottom edge.
+type textLine struct {
+	Runs    []textRun
+	X       float64
+	Y       float64
+	Right   float64
+	Size    float64 // size of the majority of the glyphs
+	Heading int     // heading level from the document outline, 0 if none
+	Image   string
+	Height  float64 // height of an image
+}
+
+// Text returns the plain text of the line
+func (l textLine) Text() string {
+	var b strings.Builder
+	for _, run := range l.Runs {
+		b.WriteString(run.Text)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// pageLayout is the text of one page grouped into lines
+type pageLayout struct {
+	Number int
+	Width  float64 // size of the media box
+	Height float64
+	Lines  []textLine
+	Rects  []pdf.Rect // rectangles drawn on the page, used as table rulings
+	Links  []pdfLink
+	Marked map[int][]pdf.Text // glyphs by marked-content ID, for tagged PDFs
+}
+
+// buildLines groups the glyphs of a page into lines and runs in content
+// stream order. Glyph widths are estimated when the PDF does not report them,
+// which is the case for many embedded TrueType fonts. Glyphs covered by a
+// link annotation are split into runs carrying its URL.
+func buildLines(texts []pdf.Text, links []pdfLink) []textLine {
+	var lines []textLine
+	var line *textLine
+	pen := 0.0
+
+	for _, t := range texts {
+		if !isVisibleGlyph(t.S) {
+			continue
+		}
+
+		if line == nil || math.Abs(t.Y-line.Y) > t.FontSize*sameLineTolerance {
+			lines = append(lines, textLine{X: t.X, Y: t.Y, Right: t.X})
+			line = &lines[len(lines)-1]
+			pen = t.X
+		}
+
+		// A gap starts a new run so that table cells can be told apart later
+		text := t.S
+		gap := t.X-pen > t.FontSize*wordGapRatio
+		if gap && !strings.HasPrefix(text, " ") && !endsWithSpace(line.Runs) {
+			text = " " + text
+		}
+
+		width := t.W
+		if width <= 0 {
+			width = glyphWidthRatio * t.FontSize * float64(len([]rune(t.S)))
+		}
+		link := ""
+		if len(links) > 0 && strings.TrimSpace(t.S) != "" {
+			// Estimated positions drift, so without widths only the start of
+			// each text chunk is tested
+			x, w := math.Max(pen, t.X), width
+			if t.W <= 0 {
+				x, w = t.X, glyphWidthRatio*t.FontSize
+			}
+			link = linkAt(links, x, w, t.Y, t.FontSize)
+		}
+
+		if n := len(line.Runs); n > 0 && !gap && line.Runs[n-1].Font == t.Font && line.Runs[n-1].Size == t.FontSize &&
+			(line.Runs[n-1].Link == link || strings.TrimSpace(t.S) == "") {
+			line.Runs[n-1].Text += text
+		} else {
+			line.Runs = append(line.Runs, textRun{Text: text, Font: t.Font, Size: t.FontSize, X: t.X, Link: link})
+		}
+
+		pen = math.Max(pen, t.X) + width
+		line.Runs[len(line.Runs)-1].Right = pen
+		line.X = math.Min(line.X, t.X)
+		line.Right = math.Max(line.Right, pen)
+	}
+
+	for i := range lines {
+		lines[i].Size = dominantSize(lines[i].Runs)
+	}
+
+	// Drop lines that only contained spaces
+	kept := lines[:0]
+	for _, l := range lines {
+		if l.Text() != "" {
+			kept = append(kept, l)
+		}
+	}
+	return kept
+}
+
+// isVisibleGlyph reports whether s is text worth keeping. Extraction yields
+// control characters and U+FFFD for glyphs without a Unicode mapping.
+func isVisibleGlyph(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r == unicode.ReplacementChar || (unicode.IsControl(r) && r != '\t') {
+			return false
+		}
+	}
+	return true
+}
+
+// endsWithSpace reports whether the last run ends with whitespace
+func endsWithSpace(runs []textRun) bool {
+	if len(runs) == 0 {
+		return true
+	}
+	text := runs[len(runs)-1].Text
+	return text == "" || strings.HasSuffix(text, " ")
+}
+
+// dominantSize returns the font size used by most characters of the runs
+func dominantSize(runs []textRun) float64 {
+	counts := make(map[float64]int)
+	best := 0.0
+	for _, run := range runs {
+		counts[run.Size] += len(strings.TrimSpace(run.Text))
+		if counts[run.Size] > counts[best] {
+			best = run.Size
+		}
+	}
+	return best
+}
+
+// roundSize rounds a font size to half points so that sizes differing only
+// by rounding errors are clustered together
+func roundSize(size float64) float64 {
+	return math.Round(size*2) / 2
+}
+
+// countSizes adds the characters of a line to counts, the number of
+// characters set in each font size of a document
+func countSizes(counts map[float64]int, line textLine) {
+	for _, run := range line.Runs {
+		counts[roundSize(run.Size)] += len(strings.TrimSpace(run.Text))
+	}
+}
+
+// headingLevels maps the font sizes used in a document, counted by
+// countSizes, to heading levels. The most common size is body text; the
+// largest sizes above it become levels 1 to 3, and any smaller heading
+// sizes share the last level.
+func headingLevels(counts map[float64]int) map[float64]int {
+	body := 0.0
+	for size, n := range counts {
+		if n > counts[body] || (n == counts[body] && size < body) {
+			body = size
+		}
+	}
+
+	var sizes []float64
+	for size := range counts {
+		if size >= body*headingSizeRatio {
+			sizes = append(sizes, size)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.Float64Slice(sizes)))
+
+	levels := make(map[float64]int)
+	for i, size := range sizes {
+		levels[size] = min(i+1, maxHeadingLevels)
+	}
+	return levels
+}
+
+// headingLevel returns the heading level of a line, or 0 for body text
+func headingLevel(line textLine, levels map[float64]int) int {
+	if line.Heading > 0 {
+		return line.Heading
+	}
+	if len([]rune(line.Text())) > maxHeadingLength {
+		return 0
+	}
+	return levels[roundSize(line.Size)]
+}
+
+// pdfBlock is a heading, paragraph, list item, code block, table or image
+// made of consecutive lines
+type pdfBlock struct {
+	Level int        // heading level, 0 for paragraphs and code
+	Code  bool       // all lines are set in a monospace font
+	Table [][]string // table cells in Markdown, the first row is the header
+	Image string     // path of an image
+	Item  string     // Markdown marker of a list item, "-" or a number such as "3."
+	Depth int        // nesting depth of a list item
+	Runs  []textRun  // text of a paragraph or list item reflowed into one line
+	Lines []textLine
+}
+
+// buildBlocks groups the lines of a page into headings, paragraphs, list
+// items, code blocks and, unless tables is tablesOff, tables. The text of
+// paragraphs and list items is reflowed, using the words of the document to
+// join words hyphenated at line ends.
+func buildBlocks(page pageLayout, levels map[float64]int, tables string, words map[string]bool) []pdfBlock {
+	lines := page.Lines
+	found := findTables(page, tables)
+
+	var blocks []pdfBlock
+	var listX []float64 // positions of the enclosing list items
+	markers := make(map[int]int)
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		if table, ok := found[i]; ok {
+			blocks = append(blocks, pdfBlock{Table: table.rows, Lines: lines[i:table.end]})
+			i = table.end - 1
+			continue
+		}
+		if line.Image != "" {
+			blocks = append(blocks, pdfBlock{Image: line.Image, Lines: []textLine{line}})
+			continue
+		}
+
+		code := isCodeLine(line)
+		level := 0
+		if !code {
+			level = headingLevel(line, levels)
+		}
+		item, marker := "", 0
+		if !code && level == 0 {
+			item, marker = listMarker(line.Text())
+		}
+
+		gap := 0.0
+		if i > 0 {
+			gap = lines[i-1].Y - line.Y
+		}
+		near := i > 0 && gap > 0 && gap <= lines[i-1].Size*paragraphGapRatio
+
+		if n := len(blocks); n > 0 && i > 0 && blocks[n-1].Table == nil && blocks[n-1].Image == "" && item == "" && near {
+			last := &blocks[n-1]
+			switch {
+			case last.Item != "":
+				// Lines indented past the marker continue the item
+				if level == 0 && !code && line.X > last.Lines[0].X+line.Size*nestingTolerance {
+					last.Lines = append(last.Lines, line)
+					continue
+				}
+			case last.Level == level && last.Code == code:
+				if level > 0 || code || !startsParagraph(last.Lines, line) {
+					last.Lines = append(last.Lines, line)
+					continue
+				}
+			}
+		}
+
+		block := pdfBlock{Level: level, Code: code, Lines: []textLine{line}}
+		if item != "" {
+			// Items continue a list when they follow one of its items
+			if n := len(blocks); n == 0 || blocks[n-1].Item == "" || !near {
+				listX = nil
+			}
+			block.Item = item
+			block.Depth, listX = listDepth(line.X, line.Size, listX)
+			markers[len(blocks)] = marker
+		}
+		blocks = append(blocks, block)
+	}
+
+	for i := range blocks {
+		block := &blocks[i]
+		if block.Level > 0 || block.Code || block.Table != nil || block.Image != "" {
+			continue
+		}
+		block.Runs = reflow(block.Lines, words)
+		if block.Item != "" {
+			block.Runs = stripMarker(block.Runs, markers[i])
+		}
+	}
+	return blocks
+}
+
+// writeBlocks writes blocks as Markdown headings, paragraphs, lists, code blocks, tables and images
+func writeBlocks(b *strings.Builder, blocks []pdfBlock) {
+	var widths []int // marker widths of the enclosing list items
+	for i, block := range blocks {
+		if block.Item != "" {
+			// The items of a list are written without blank lines between them
+			if i > 0 && blocks[i-1].Item == "" {
+				b.WriteString("\n")
+				widths = nil
+			}
+			widths = writeListItem(b, block, widths)
+			continue
+		}
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		widths = nil
+
+		if block.Image != "" {
+			b.WriteString("![](" + block.Image + ")\n")
+			continue
+		}
+
+		if block.Table != nil {
+			writeTable(b, block.Table)
+			continue
+		}
+
+		if block.Code {
+			writeCodeBlock(b, block.Lines)
+			continue
+		}
+
+		if block.Level > 0 {
+			texts := make([]string, len(block.Lines))
+			for j, line := range block.Lines {
+				texts[j] = line.Text()
+			}
+			b.WriteString(strings.Repeat("#", block.Level) + " " + strings.Join(texts, " ") + "\n")
+			continue
+		}
+
+		b.WriteString(inlineMarkdown(block.Runs) + "\n")
+	}
+}
@@ -0,0 +1,71 @@
+package converter
+
+import (
+	"math"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// linkBaselineRatio is the height above the baseline, relative to the font
+// size, at which glyphs are tested against link rectangles
+const linkBaselineRatio = 0.3
+
+// pdfLink is a URI link annotation and the area of the page it covers
+type pdfLink struct {
+	X0, Y0, X1, Y1 float64
+	URL            string
+}
+
+// contains reports whether a point lies in the link area
+func (l pdfLink) contains(x, y float64) bool {
+	return x >= l.X0 && x <= l.X1 && y >= l.Y0 && y <= l.Y1
+}
+
+// pageLinks returns the URI link annotations of a page. Links to
+// destinations inside the document are ignored.
+func pageLinks(page pdf.Page) []pdfLink {
+	annots := page.V.Key("Annots")
+	var links []pdfLink
+	for i := 0; i < annots.Len(); i++ {
+		annot := annots.Index(i)
+		if annot.Key("Subtype").Name() != "Link" {
+			continue
+		}
+		action := annot.Key("A")
+		rect := annot.Key("Rect")
+		if action.Key("S").Name() != "URI" || rect.Len() != 4 {
+			continue
+		}
+		url := strings.TrimSpace(action.Key("URI").RawString())
+		if url == "" {
+			continue
+		}
+
+		x0, y0 := rect.Index(0).Float64(), rect.Index(1).Float64()
+		x1, y1 := rect.Index(2).Float64(), rect.Index(3).Float64()
+		links = append(links, pdfLink{
+			X0:  math.Min(x0, x1),
+			Y0:  math.Min(y0, y1),
+			X1:  math.Max(x0, x1),
+			Y1:  math.Max(y0, y1),
+			URL: url,
+		})
+	}
+	return links
+}
+
+// linkAt returns the URL of the link covering a glyph, or "" if there is none
+func linkAt(links []pdfLink, x, width, baseline, size float64) string {
+	for _, link := range links {
+		if link.contains(x+width/2, baseline+size*linkBaselineRatio) {
+			return link.URL
+		}
+	}
+	return ""
+}
+
+// markdownURL escapes the characters of a URL that would end a Markdown link
+func markdownURL(url string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29", "<", "%3C", ">", "%3E").Replace(url)
+}
@@ -0,0 +1,82 @@
+package converter
+
+import (
+	"regexp"
+	"strings"
+)
+
+// nestingTolerance is the indentation, relative to the font size, within
+// which list items count as being at the same depth
+const nestingTolerance = 0.5
+
+var (
+	// bulletRegex matches the bullets of unordered list items, including the
+	// private-use bullets of the Symbol and Wingdings fonts
+	bulletRegex = regexp.MustCompile(`^([•◦▪▫‣⁃∙·○●■□➢►–*\-\x{F0B7}\x{F0A7}\x{F0D8}])\s+`)
+	// numberRegex matches the numbers of ordered list items, e.g. "3." or "3)"
+	numberRegex = regexp.MustCompile(`^(\d{1,3})[.)]\s+`)
+)
+
+// listMarker returns the Markdown marker of a list item line, "-" or a
+// number such as "3.", and the length of the marker in the line's text.
+// It returns "" for other lines.
+func listMarker(text string) (string, int) {
+	if m := numberRegex.FindStringSubmatch(text); m != nil {
+		return m[1] + ".", len(m[0])
+	}
+	if m := bulletRegex.FindString(text); m != "" {
+		return "-", len(m)
+	}
+	return "", 0
+}
+
+// listDepth returns the nesting depth of a list item at x given the
+// positions of the enclosing items, and updates them
+func listDepth(x, size float64, stack []float64) (int, []float64) {
+	tolerance := size * nestingTolerance
+	for len(stack) > 0 && x < stack[len(stack)-1]-tolerance {
+		stack = stack[:len(stack)-1]
+	}
+	if len(stack) == 0 || x > stack[len(stack)-1]+tolerance {
+		stack = append(stack, x)
+	}
+	return len(stack) - 1, stack
+}
+
+// stripMarker removes the list marker, marker bytes long in the line's
+// text without leading spaces, from the start of a line's runs
+func stripMarker(runs []textRun, marker int) []textRun {
+	runs = append([]textRun(nil), runs...)
+	leading := true
+	for len(runs) > 0 {
+		text := runs[0].Text
+		if leading {
+			if text = strings.TrimLeft(text, " "); text == "" {
+				runs = runs[1:]
+				continue
+			}
+			leading = false
+		}
+		if marker < len(text) {
+			runs[0].Text = text[marker:]
+			return runs
+		}
+		marker -= len(text)
+		runs = runs[1:]
+	}
+	return runs
+}
+
+// writeListItem writes a list item indented below the items enclosing it.
+// widths holds the marker widths of those items and is returned updated.
+func writeListItem(b *strings.Builder, block pdfBlock, widths []int) []int {
+	if block.Depth < len(widths) {
+		widths = widths[:block.Depth]
+	}
+	indent := 0
+	for _, w := range widths {
+		indent += w
+	}
+	b.WriteString(strings.Repeat(" ", indent) + block.Item + " " + inlineMarkdown(block.Runs) + "\n")
+	return append(widths, len(block.Item)+1)
+}
@@ -0,0 +1,181 @@
+package converter
+
+import (
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// maxFieldDepth limits the nesting of form fields that is followed
+const maxFieldDepth = 16
+
+// infoKeys maps the entries of the document Info dictionary to front matter
+// and metadata keys, in the order they are written
+var infoKeys = []struct{ entry, key string }{
+	{"Title", "title"},
+	{"Author", "author"},
+	{"Subject", "subject"},
+	{"Keywords", "keywords"},
+	{"CreationDate", "date"},
+	{"Producer", "producer"},
+}
+
+// pdfDateRegex matches PDF dates such as "D:20240315093000+01'00'"
+var pdfDateRegex = regexp.MustCompile(`^(?:D:)?(\d{4})(\d{2})?(\d{2})?(\d{2})?(\d{2})?(\d{2})?(?:([Zz+-])(\d{2})?'?(\d{2})?'?)?`)
+
+// pdfDocumentInfo returns the entries of the document Info dictionary that are
+// set, keyed as in infoKeys. Dates are converted to RFC 3339.
+func pdfDocumentInfo(reader *pdf.Reader) (values map[string]string) {
+	values = make(map[string]string)
+	defer func() {
+		// The PDF library panics on malformed objects
+		_ = recover()
+	}()
+
+	info := reader.Trailer().Key("Info")
+	for _, k := range infoKeys {
+		value := strings.TrimSpace(info.Key(k.entry).Text())
+		if k.entry == "CreationDate" {
+			value = pdfDate(value)
+		}
+		if value != "" {
+			values[k.key] = value
+		}
+	}
+	return values
+}
+
+// pdfDate converts a PDF date to RFC 3339, or returns it unchanged if it
+// cannot be parsed
+func pdfDate(value string) string {
+	m := pdfDateRegex.FindStringSubmatch(value)
+	if m == nil {
+		return value
+	}
+	num := func(s string, def int) int {
+		if n, err := strconv.Atoi(s); err == nil {
+			return n
+		}
+		return def
+	}
+	loc := time.UTC
+	switch m[7] {
+	case "+", "-":
+		offset := num(m[8], 0)*3600 + num(m[9], 0)*60
+		if m[7] == "-" {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+	}
+	t := time.Date(num(m[1], 0), time.Month(num(m[2], 1)), num(m[3], 1), num(m[4], 0), num(m[5], 0), num(m[6], 0), 0, loc)
+	return t.Format(time.RFC3339)
+}
+
+// writeFrontMatter writes document information as a YAML front matter block
+func writeFrontMatter(b *strings.Builder, info map[string]string) {
+	b.WriteString("---\n")
+	for _, k := range infoKeys {
+		if value, ok := info[k.key]; ok {
+			b.WriteString(k.key + ": " + strconv.Quote(value) + "\n")
+		}
+	}
+	b.WriteString("---\n\n")
+}
+
+// formField is a terminal field of an interactive form
+type formField struct {
+	Name  string // fully qualified name, e.g. "address.city"
+	Value string
+}
+
+// formFields returns the fields of the document's interactive form in
+// document order, named by their fully qualified names. Reading stops at
+// the first malformed field.
+func formFields(reader *pdf.Reader) (fields []formField) {
+	defer func() {
+		// The PDF library panics on malformed objects
+		_ = recover()
+	}()
+
+	var walk func(field pdf.Value, parent string, depth int)
+	walk = func(field pdf.Value, parent string, depth int) {
+		name := parent
+		if partial := field.Key("T").Text(); partial != "" {
+			if name != "" {
+				name += "."
+			}
+			name += partial
+		}
+
+		// Kids without a name of their own are widgets of this field
+		kids := field.Key("Kids")
+		named := false
+		for i := 0; i < kids.Len(); i++ {
+			if !kids.Index(i).Key("T").IsNull() {
+				named = true
+			}
+		}
+		if named && depth < maxFieldDepth {
+			for i := 0; i < kids.Len(); i++ {
+				walk(kids.Index(i), name, depth+1)
+			}
+			return
+		}
+		if name != "" {
+			fields = append(fields, formField{Name: name, Value: fieldValue(field)})
+		}
+	}
+
+	roots := reader.Trailer().Key("Root").Key("AcroForm").Key("Fields")
+	for i := 0; i < roots.Len(); i++ {
+		walk(roots.Index(i), "", 0)
+	}
+	return fields
+}
+
+// fieldValue returns the value of a form field as text. Check boxes and
+// radio buttons have names as values, and list boxes may select several
+// options.
+func fieldValue(field pdf.Value) string {
+	value := field.Key("V")
+	for p, depth := field, 0; value.IsNull() && !p.Key("Parent").IsNull() && depth < maxFieldDepth; depth++ {
+		// Values may be inherited from the parent field
+		p = p.Key("Parent")
+		value = p.Key("V")
+	}
+
+	switch value.Kind() {
+	case pdf.Name:
+		return value.Name()
+	case pdf.String:
+		return value.Text()
+	case pdf.Array:
+		items := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			items = append(items, value.Index(i).Text())
+		}
+		return strings.Join(items, ", ")
+	case pdf.Stream:
+		// Long text values may be stored in a stream
+		rd := value.Reader()
+		defer rd.Close()
+		data, _ := io.ReadAll(rd)
+		return string(data)
+	default:
+		return ""
+	}
+}
+
+// writeFormFields writes form fields as a Markdown table
+func writeFormFields(b *strings.Builder, fields []formField) {
+	rows := [][]string{{"Field", "Value"}}
+	for _, field := range fields {
+		rows = append(rows, []string{field.Name, strings.Join(strings.Fields(field.Value), " ")})
+	}
+	b.WriteString("## Form fields\n\n")
+	writeTable(b, rows)
+}
@@ -0,0 +1,203 @@
+package converter
+
+import (
+	"math"
+	"sort"
+	"strings"
+)
+
+// Reading order heuristics
+const (
+	fragmentGapRatio = 1.0  // gap, relative to the font size, that splits a line into fragments
+	ascentRatio      = 0.8  // height of text above the baseline relative to the font size
+	descentRatio     = 0.25 // depth of text below the baseline relative to the font size
+	minColumnLines   = 3    // fewest fragments on each side of a column gutter
+	minColumnText    = 20   // smallest average fragment length, in characters, of a text column
+)
+
+// readingOrder arranges the lines of a page in natural reading order. Lines
+// are split into fragments at wide gaps, the page is segmented recursively
+// into blocks by whitespace (XY-cut), preferring column gutters over
+// horizontal gaps so that columns are read one after the other, and the
+// fragments of each block are joined back into lines.
+func readingOrder(lines []textLine) []textLine {
+	var ordered []textLine
+	for _, block := range xyCut(splitFragments(lines)) {
+		ordered = append(ordered, joinFragments(block)...)
+	}
+	return ordered
+}
+
+// splitFragments splits lines at gaps wider than the font size
+func splitFragments(lines []textLine) []textLine {
+	var fragments []textLine
+	for _, line := range lines {
+		if line.Image != "" {
+			fragments = append(fragments, line)
+			continue
+		}
+		var current *textLine
+		for _, run := range line.Runs {
+			if current == nil || run.X-current.Right > run.Size*fragmentGapRatio {
+				fragments = append(fragments, textLine{X: run.X, Y: line.Y, Right: run.Right})
+				current = &fragments[len(fragments)-1]
+			}
+			current.Runs = append(current.Runs, run)
+			current.X = math.Min(current.X, run.X)
+			current.Right = math.Max(current.Right, run.Right)
+		}
+	}
+	kept := fragments[:0]
+	for _, f := range fragments {
+		if f.Image != "" || f.Text() != "" {
+			f.Size = dominantSize(f.Runs)
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// top returns the upper edge of a line or image
+func top(l textLine) float64 {
+	if l.Image != "" {
+		return l.Y + l.Height
+	}
+	return l.Y + l.Size*ascentRatio
+}
+
+// bottom returns the lower edge of a line or image
+func bottom(l textLine) float64 {
+	if l.Image != "" {
+		return l.Y
+	}
+	return l.Y - l.Size*descentRatio
+}
+
+// xyCut segments fragments into blocks in reading order
+func xyCut(fragments []textLine) [][]textLine {
+	if len(fragments) < 2 {
+		return [][]textLine{fragments}
+	}
+	if left, right, ok := columnCut(fragments); ok {
+		return append(xyCut(left), xyCut(right)...)
+	}
+	if above, below, ok := horizontalCut(fragments); ok {
+		return append(xyCut(above), xyCut(below)...)
+	}
+	return [][]textLine{fragments}
+}
+
+// columnCut splits fragments at the widest vertical gutter that runs through
+// all of them. Both sides must look like columns of running text, which keeps
+// table columns together.
+func columnCut(fragments []textLine) ([]textLine, []textLine, bool) {
+	sorted := append([]textLine(nil), fragments...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].X < sorted[j].X })
+
+	bestGap, cut := 0.0, 0.0
+	reach := sorted[0].Right
+	for _, f := range sorted[1:] {
+		if gap := f.X - reach; gap > bestGap && gap > f.Size*fragmentGapRatio {
+			bestGap, cut = gap, f.X
+		}
+		reach = math.Max(reach, f.Right)
+	}
+	if bestGap == 0 {
+		return nil, nil, false
+	}
+
+	var left, right []textLine
+	for _, f := range fragments {
+		if f.X < cut {
+			left = append(left, f)
+		} else {
+			right = append(right, f)
+		}
+	}
+	if !isTextColumn(left) || !isTextColumn(right) {
+		return nil, nil, false
+	}
+	return left, right, true
+}
+
+// isTextColumn reports whether fragments look like a column of running text
+func isTextColumn(fragments []textLine) bool {
+	if len(fragments) < minColumnLines {
+		return false
+	}
+	chars := 0
+	for _, f := range fragments {
+		chars += len([]rune(f.Text()))
+	}
+	return chars/len(fragments) >= minColumnText
+}
+
+// horizontalCut splits fragments at the widest horizontal gap that runs across all of them
+func horizontalCut(fragments []textLine) ([]textLine, []textLine, bool) {
+	sorted := append([]textLine(nil), fragments...)
+	sort.Slice(sorted, func(i, j int) bool { return top(sorted[i]) > top(sorted[j]) })
+
+	bestGap, cut := 0.0, 0.0
+	reach := bottom(sorted[0])
+	for _, f := range sorted[1:] {
+		if gap := reach - top(f); gap > bestGap {
+			bestGap, cut = gap, top(f)
+		}
+		reach = math.Min(reach, bottom(f))
+	}
+	if bestGap == 0 {
+		return nil, nil, false
+	}
+
+	var above, below []textLine
+	for _, f := range fragments {
+		if top(f) > cut {
+			above = append(above, f)
+		} else {
+			below = append(below, f)
+		}
+	}
+	return above, below, true
+}
+
+// joinFragments joins the fragments of a block that share a baseline back
+// into lines, ordered top to bottom and left to right
+func joinFragments(fragments []textLine) []textLine {
+	sorted := append([]textLine(nil), fragments...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Y > sorted[j].Y })
+
+	// Group fragments into rows by baseline, then order each row by position.
+	// Images always form rows of their own.
+	var rows [][]textLine
+	for _, f := range sorted {
+		if n := len(rows); n > 0 && f.Image == "" {
+			first := rows[n-1][0]
+			if first.Image == "" && first.Y-f.Y <= math.Max(first.Size, f.Size)*sameLineTolerance {
+				rows[n-1] = append(rows[n-1], f)
+				continue
+			}
+		}
+		rows = append(rows, []textLine{f})
+	}
+
+	lines := make([]textLine, 0, len(rows))
+	for _, row := range rows {
+		if row[0].Image != "" {
+			lines = append(lines, row[0])
+			continue
+		}
+		sort.SliceStable(row, func(i, j int) bool { return row[i].X < row[j].X })
+		line := textLine{X: row[0].X, Y: row[0].Y, Right: row[0].Right}
+		for _, f := range row {
+			runs := append([]textRun(nil), f.Runs...)
+			if !endsWithSpace(line.Runs) && !strings.HasPrefix(runs[0].Text, " ") {
+				runs[0].Text = " " + runs[0].Text
+			}
+			line.Runs = append(line.Runs, runs...)
+			line.Right = math.Max(line.Right, f.Right)
+		}
+		line.Size = dominantSize(line.Runs)
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,166 @@
+package converter
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Outline matching limits
+const (
+	maxOutlineDepth   = 6     // deeper outline entries share the last Markdown heading level
+	maxTitleLines     = 3     // lines a heading matching an outline title may wrap over
+	maxOutlineEntries = 10000 // guards against cycles in malformed outlines
+)
+
+// outlineEntry is a bookmark of the document outline with its heading level
+type outlineEntry struct {
+	key   string // normalized title
+	level int
+	page  int // page number of the destination, 0 if unknown
+	used  bool
+}
+
+// readOutline returns the entries of the document outline in document
+// order, with the pages they point to
+func readOutline(reader *pdf.Reader) (entries []outlineEntry) {
+	defer func() {
+		// The PDF library panics on malformed objects
+		if r := recover(); r != nil {
+			entries = nil
+		}
+	}()
+
+	root := reader.Trailer().Key("Root")
+	pages := pageNumbers(reader)
+	var walk func(item pdf.Value, depth int)
+	walk = func(item pdf.Value, depth int) {
+		for ; item.Kind() == pdf.Dict && len(entries) < maxOutlineEntries && depth <= maxPageTreeDepth; item = item.Key("Next") {
+			if key := titleKey(item.Key("Title").Text()); key != "" {
+				entries = append(entries, outlineEntry{
+					key:   key,
+					level: min(depth, maxOutlineDepth),
+					page:  destinationPage(root, item, pages),
+				})
+			}
+			walk(item.Key("First"), depth+1)
+		}
+	}
+	walk(root.Key("Outlines").Key("First"), 1)
+	return entries
+}
+
+// destinationPage returns the page number an outline item points to, given
+// as an explicit destination or a named one, directly or in a GoTo action
+func destinationPage(root, item pdf.Value, pages map[string]int) int {
+	dest := item.Key("Dest")
+	if dest.IsNull() && item.Key("A").Key("S").Name() == "GoTo" {
+		dest = item.Key("A").Key("D")
+	}
+	if dest.Kind() == pdf.Name || dest.Kind() == pdf.String {
+		dest = namedDestination(root, dest)
+	}
+	if dest.Kind() == pdf.Dict {
+		dest = dest.Key("D")
+	}
+	if dest.Kind() != pdf.Array || dest.Len() == 0 {
+		return 0
+	}
+	return pages[dest.Index(0).String()]
+}
+
+// namedDestination looks a named destination up in the catalog's Dests
+// dictionary (PDF 1.1) or the Dests name tree
+func namedDestination(root, name pdf.Value) pdf.Value {
+	if name.Kind() == pdf.Name {
+		return root.Key("Dests").Key(name.Name())
+	}
+
+	key := name.RawString()
+	node := root.Key("Names").Key("Dests")
+	for depth := 0; depth < maxPageTreeDepth && node.Kind() == pdf.Dict; depth++ {
+		if names := node.Key("Names"); names.Kind() == pdf.Array {
+			for i := 0; i+1 < names.Len(); i += 2 {
+				if names.Index(i).RawString() == key {
+					return names.Index(i + 1)
+				}
+			}
+			return pdf.Value{}
+		}
+
+		// Descend into the kid whose range holds the name
+		kids, next := node.Key("Kids"), pdf.Value{}
+		for i := 0; i < kids.Len(); i++ {
+			limits := kids.Index(i).Key("Limits")
+			if limits.Len() == 2 && limits.Index(0).RawString() <= key && key <= limits.Index(1).RawString() {
+				next = kids.Index(i)
+				break
+			}
+		}
+		node = next
+	}
+	return pdf.Value{}
+}
+
+// titleKey normalizes a title for matching against page text, keeping only
+// letters and digits so that differences in spacing and punctuation (such as
+// "1 Introduction" and "1. Introduction") do not matter
+func titleKey(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(title) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// applyOutline marks the lines of a page whose text matches an outline
+// title as headings at the depth of the entry. A title may wrap over several
+// lines; each entry is matched once, and not on pages before its
+// destination, so that a table of contents does not use the entries up.
+// Pages must be passed in order. It returns the number of entries matched.
+func applyOutline(page *pageLayout, entries []outlineEntry) int {
+	if len(entries) == 0 {
+		return 0
+	}
+
+	matched := 0
+	lines := page.Lines
+	for i := 0; i < len(lines); i++ {
+		if lines[i].Image != "" || len([]rune(lines[i].Text())) > maxHeadingLength {
+			continue
+		}
+
+		key := ""
+	extend:
+		for j := i; j < len(lines) && j < i+maxTitleLines && lines[j].Image == ""; j++ {
+			key += titleKey(lines[j].Text())
+			if key == "" {
+				break
+			}
+			prefix := false
+			for e := range entries {
+				entry := &entries[e]
+				if entry.used || entry.page > page.Number || !strings.HasPrefix(entry.key, key) {
+					continue
+				}
+				if entry.key == key {
+					entry.used = true
+					matched++
+					for k := i; k <= j; k++ {
+						lines[k].Heading = entry.level
+					}
+					i = j
+					break extend
+				}
+				prefix = true
+			}
+			if !prefix {
+				break
+			}
+		}
+	}
+	return matched
+}
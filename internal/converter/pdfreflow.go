@@ -0,0 +1,100 @@
+package converter
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Paragraph heuristics, relative to the font size of the text involved
+const (
+	spacingGrowthRatio = 1.2 // line spacing, relative to the paragraph's, that starts a new paragraph
+	indentRatio        = 1.0 // first-line indentation that starts a new paragraph
+)
+
+var (
+	// wordRegex matches words, including hyphenated compounds such as "well-known"
+	wordRegex = regexp.MustCompile(`\p{L}+(?:-\p{L}+)*`)
+	// hyphenHeadRegex matches the part of a word hyphenated at the end of a line
+	hyphenHeadRegex = regexp.MustCompile(`(\p{L}+)-$`)
+	// hyphenTailRegex matches the rest of the word at the start of the next line
+	hyphenTailRegex = regexp.MustCompile(`^(\p{L}+)`)
+)
+
+// startsParagraph reports whether a line begins a new paragraph after the
+// lines of the current one: the line spacing grows, or the line is indented
+// past the left edge of the paragraph.
+func startsParagraph(paragraph []textLine, line textLine) bool {
+	n := len(paragraph)
+	prev := paragraph[n-1]
+	if n >= 2 {
+		spacing := paragraph[n-2].Y - prev.Y
+		if spacing > 0 && prev.Y-line.Y > spacing*spacingGrowthRatio {
+			return true
+		}
+	}
+
+	left := math.Inf(1)
+	for _, l := range paragraph {
+		left = math.Min(left, l.X)
+	}
+	return line.X > left+line.Size*indentRatio && prev.X <= left+line.Size*indentRatio
+}
+
+// lineWords returns the lower-cased words of a line. The words of a
+// document decide how words hyphenated at line ends are joined.
+func lineWords(line textLine) []string {
+	words := wordRegex.FindAllString(line.Text(), -1)
+	for i, word := range words {
+		words[i] = strings.ToLower(word)
+	}
+	return words
+}
+
+// reflow joins the lines of a paragraph into one sequence of runs. Words
+// hyphenated at a line end are joined without the hyphen when the joined
+// form is plausible.
+func reflow(lines []textLine, words map[string]bool) []textRun {
+	var runs []textRun
+	for _, line := range lines {
+		next := append([]textRun(nil), line.Runs...)
+		if len(runs) > 0 && len(next) > 0 {
+			last := &runs[len(runs)-1]
+			text := strings.TrimRight(last.Text, " ")
+			next[0].Text = strings.TrimLeft(next[0].Text, " ")
+
+			head := hyphenHeadRegex.FindStringSubmatch(text)
+			tail := hyphenTailRegex.FindStringSubmatch(line.Text())
+			switch {
+			case head != nil && tail != nil && dehyphenate(head[1], tail[1], words):
+				last.Text = strings.TrimSuffix(text, "-")
+			case head != nil:
+				// Keep the hyphen of compounds such as "well-known" or "pre-2020"
+				last.Text = text
+			default:
+				last.Text = text + " "
+			}
+		}
+		runs = append(runs, next...)
+	}
+	return runs
+}
+
+// dehyphenate reports whether a word split into head and tail by a hyphen at
+// the end of a line is a single word. Words seen elsewhere in the document
+// decide; otherwise lower-case continuations are taken as one word.
+func dehyphenate(head, tail string, words map[string]bool) bool {
+	if words[strings.ToLower(head+tail)] {
+		return true
+	}
+	if words[strings.ToLower(head+"-"+tail)] {
+		return false
+	}
+	for i, r := range head + tail {
+		if i > 0 && !unicode.IsLower(r) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,155 @@
+package converter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Split modes of the "split" option
+const (
+	splitPage    = "page"
+	splitHeading = "heading"
+)
+
+// maxSlugLength limits the heading part of split file names
+const maxSlugLength = 50
+
+// parsePageRanges parses a page selection such as "3-10,15" into sorted,
+// unique page numbers. An empty selection selects all pages.
+func parsePageRanges(spec string, numPages int) ([]int, error) {
+	if strings.TrimSpace(spec) == "" {
+		pages := make([]int, numPages)
+		for i := range pages {
+			pages[i] = i + 1
+		}
+		return pages, nil
+	}
+
+	seen := make(map[int]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+
+		from, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil {
+			return nil, fmt.Errorf("invalid page range %q", part)
+		}
+		to := from
+		if isRange {
+			// An open range such as "5-" runs to the last page
+			if strings.TrimSpace(last) == "" {
+				to = numPages
+			} else if to, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+				return nil, fmt.Errorf("invalid page range %q", part)
+			}
+		}
+
+		if from < 1 || to < from {
+			return nil, fmt.Errorf("invalid page range %q", part)
+		}
+		if to > numPages {
+			return nil, fmt.Errorf("page range %q exceeds the %d pages of the document", part, numPages)
+		}
+		for p := from; p <= to; p++ {
+			seen[p] = true
+		}
+	}
+
+	pages := make([]int, 0, len(seen))
+	for p := range seen {
+		pages = append(pages, p)
+	}
+	sort.Ints(pages)
+	return pages, nil
+}
+
+// markdownFile is one output file of a split conversion
+type markdownFile struct {
+	name   string
+	blocks []pdfBlock
+}
+
+// splitPages returns one file per page, named after the page number
+func splitPages(pages []pageLayout, blocks [][]pdfBlock, numPages int) []markdownFile {
+	width := len(strconv.Itoa(numPages))
+	files := make([]markdownFile, 0, len(pages))
+	for i, page := range pages {
+		files = append(files, markdownFile{
+			name:   fmt.Sprintf("page-%0*d.md", width, page.Number),
+			blocks: blocks[i],
+		})
+	}
+	return files
+}
+
+// splitHeadings returns one file per top-level heading, numbered in document
+// order and named after the heading. Content before the first heading goes
+// to a file numbered 00.
+func splitHeadings(blocks [][]pdfBlock) []markdownFile {
+	var files []markdownFile
+	for _, pageBlocks := range blocks {
+		for _, block := range pageBlocks {
+			if block.Level == 1 {
+				title := make([]string, len(block.Lines))
+				for i, line := range block.Lines {
+					title[i] = line.Text()
+				}
+				files = append(files, markdownFile{name: slugify(strings.Join(title, " "))})
+			} else if len(files) == 0 {
+				files = append(files, markdownFile{name: "preamble"})
+			}
+			files[len(files)-1].blocks = append(files[len(files)-1].blocks, block)
+		}
+	}
+
+	// Number the files, counting from 00 if there is a preamble
+	offset := 1
+	if len(files) > 0 && files[0].blocks[0].Level != 1 {
+		offset = 0
+	}
+	width := max(2, len(strconv.Itoa(len(files))))
+	for i := range files {
+		files[i].name = fmt.Sprintf("%0*d-%s.md", width, i+offset, files[i].name)
+	}
+	return files
+}
+
+// slugify turns a heading into a file name component
+func slugify(text string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	slug := strings.TrimSuffix(b.String(), "-")
+	if runes := []rune(slug); len(runes) > maxSlugLength {
+		slug = strings.TrimSuffix(string(runes[:maxSlugLength]), "-")
+	}
+	if slug == "" {
+		slug = "section"
+	}
+	return slug
+}
+
+// writeMarkdownFiles writes each file into dir, which must exist
+func writeMarkdownFiles(dir string, files []markdownFile) error {
+	for _, file := range files {
+		var markdown strings.Builder
+		writeBlocks(&markdown, file.blocks)
+		if err := os.WriteFile(filepath.Join(dir, file.name), []byte(markdown.String()), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package converter
+
+// documentStats are the statistics of a whole document that the layout
+// heuristics depend on: the running headers and footers, and the font sizes
+// and words of the remaining text, which decide heading levels and
+// hyphenation. They are gathered in a first pass over the pages, so that the
+// pages can then be converted and written one at a time.
+type documentStats struct {
+	running *runningLines
+	sizes   map[float64]int
+	words   map[string]bool
+	edges   []edgeLine // counted once it is known which are running lines
+}
+
+// edgeLine is the contribution of a line at the top or bottom of a page,
+// which only counts if the line is not a running header or footer
+type edgeLine struct {
+	key   runningKey
+	sizes []sizeCount
+	words []string
+}
+
+// sizeCount is the number of characters set in a font size
+type sizeCount struct {
+	size float64
+	n    int
+}
+
+// pageStats are the statistics of a single page
+type pageStats struct {
+	keys  []runningKey // distinct keys of the edge lines
+	edges []edgeLine
+	sizes map[float64]int
+	words []string
+}
+
+func newDocumentStats() *documentStats {
+	return &documentStats{
+		running: newRunningLines(),
+		sizes:   make(map[float64]int),
+		words:   make(map[string]bool),
+	}
+}
+
+// collectPageStats returns the statistics of a page
+func collectPageStats(page pageLayout) pageStats {
+	stats := pageStats{keys: edgeKeys(page), sizes: make(map[float64]int)}
+	edge := make(map[int]bool)
+	for _, i := range edgeLineIndexes(page.Lines) {
+		edge[i] = true
+		line := page.Lines[i]
+		e := edgeLine{key: runningLineKey(line), words: lineWords(line)}
+		sizes := make(map[float64]int)
+		countSizes(sizes, line)
+		for size, n := range sizes {
+			e.sizes = append(e.sizes, sizeCount{size, n})
+		}
+		stats.edges = append(stats.edges, e)
+	}
+	for i, line := range page.Lines {
+		if !edge[i] {
+			countSizes(stats.sizes, line)
+			stats.words = append(stats.words, lineWords(line)...)
+		}
+	}
+	return stats
+}
+
+// add merges the statistics of a page. Of the edge lines, only the words
+// the document has not used so far are kept.
+func (s *documentStats) add(page pageStats) {
+	s.running.add(page.keys)
+	for size, n := range page.sizes {
+		s.sizes[size] += n
+	}
+	for _, word := range page.words {
+		s.words[word] = true
+	}
+	for _, e := range page.edges {
+		var words []string
+		for _, word := range e.words {
+			if !s.words[word] {
+				words = append(words, word)
+			}
+		}
+		e.words = words
+		s.edges = append(s.edges, e)
+	}
+}
+
+// finish counts the edge lines that are not running lines and returns the
+// heading levels and the words of the document
+func (s *documentStats) finish() (map[float64]int, map[string]bool) {
+	for _, e := range s.edges {
+		if s.running.repeated(e.key) {
+			continue
+		}
+		for _, c := range e.sizes {
+			s.sizes[c.size] += c.n
+		}
+		for _, word := range e.words {
+			s.words[word] = true
+		}
+	}
+	s.edges = nil
+	return headingLevels(s.sizes), s.words
+}
@@ -0,0 +1,547 @@
+package converter
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Structure tree limits
+const (
+	maxStructDepth = 64 // nesting of structure elements that is followed
+	maxRoleDepth   = 8  // role map indirections that are followed
+)
+
+// Kinds of blocks in the structure tree
+const (
+	structParagraph = "paragraph"
+	structHeading   = "heading"
+	structItem      = "item"
+	structCode      = "code"
+	structTable     = "table"
+)
+
+// structBlock is a block-level element of the structure tree
+type structBlock struct {
+	Kind  string
+	Level int // heading level
+	Depth int // nesting depth of a list item
+}
+
+// structRef places marked content in the structure tree: the block it
+// belongs to, the table cell or list label within that block, and its
+// position in the logical order of the document
+type structRef struct {
+	block     int
+	row, cell int
+	label     bool
+	seq       int
+}
+
+// Marked content that is not a part of the structure tree
+const (
+	untaggedMCID = -1 // content outside the structure tree, kept as text
+	artifactMCID = -2 // artifacts such as running headers, left out
+)
+
+// structTree is the block structure of a tagged PDF
+type structTree struct {
+	blocks []structBlock
+	refs   map[int]map[int]structRef // marked content by page number and MCID
+}
+
+// structWalker builds a structTree from the structure elements of a PDF
+type structWalker struct {
+	tree  *structTree
+	roles pdf.Value      // role map from custom to standard structure types
+	pages map[string]int // page numbers by page object
+	seq   int
+}
+
+// readStructTree reads the structure tree of a tagged PDF. It returns nil
+// for untagged PDFs and for structure trees that cannot be read.
+func readStructTree(reader *pdf.Reader) (tree *structTree) {
+	defer func() {
+		// The PDF library panics on malformed objects
+		if r := recover(); r != nil {
+			tree = nil
+		}
+	}()
+
+	root := reader.Trailer().Key("Root").Key("StructTreeRoot")
+	if root.Kind() != pdf.Dict {
+		return nil
+	}
+
+	w := &structWalker{
+		tree:  &structTree{refs: make(map[int]map[int]structRef)},
+		roles: root.Key("RoleMap"),
+		pages: pageNumbers(reader),
+	}
+	w.kids(root, 0, nil, func(kid pdf.Value) {
+		w.walk(kid, 0, 1, 0, nil)
+	})
+	if len(w.tree.refs) == 0 {
+		return nil
+	}
+	return w.tree
+}
+
+// role returns the standard structure type of an element
+func (w *structWalker) role(elem pdf.Value) string {
+	kind := elem.Key("S").Name()
+	for i := 0; i < maxRoleDepth; i++ {
+		mapped := w.roles.Key(kind)
+		if mapped.Kind() != pdf.Name || mapped.Name() == kind {
+			break
+		}
+		kind = mapped.Name()
+	}
+	return kind
+}
+
+// page returns the page an element or marked-content reference is on,
+// which is inherited when it has no Pg entry
+func (w *structWalker) page(v pdf.Value, inherited int) int {
+	if pg := v.Key("Pg"); !pg.IsNull() {
+		return w.pages[pg.String()]
+	}
+	return inherited
+}
+
+// start adds a block and returns a reference to its content
+func (w *structWalker) start(block structBlock) *structRef {
+	w.tree.blocks = append(w.tree.blocks, block)
+	return &structRef{block: len(w.tree.blocks) - 1, row: -1, cell: -1}
+}
+
+// mark records the marked content mcid of a page as part of ref
+func (w *structWalker) mark(page, mcid int, ref structRef) {
+	if page == 0 || mcid < 0 {
+		return
+	}
+	if w.tree.refs[page] == nil {
+		w.tree.refs[page] = make(map[int]structRef)
+	}
+	ref.seq = w.seq
+	w.seq++
+	w.tree.refs[page][mcid] = ref
+}
+
+// walk visits a structure element. Block-level elements start blocks that
+// their content is added to; within a block, all content belongs to it.
+// list is the number of enclosing lists.
+func (w *structWalker) walk(elem pdf.Value, page, depth, list int, ref *structRef) {
+	if depth > maxStructDepth {
+		return
+	}
+	page = w.page(elem, page)
+	kind := w.role(elem)
+	visit := func(ref *structRef, list int) func(pdf.Value) {
+		return func(kid pdf.Value) {
+			w.walk(kid, page, depth+1, list, ref)
+		}
+	}
+
+	if kind == "Figure" {
+		// Labels inside figures are not part of the text
+		return
+	}
+	if ref != nil && !(kind == "L" && w.tree.blocks[ref.block].Kind == structItem) {
+		w.kids(elem, page, ref, visit(ref, list))
+		return
+	}
+
+	switch kind {
+	case "H", "H1", "H2", "H3", "H4", "H5", "H6":
+		level := 1
+		if len(kind) == 2 {
+			level = int(kind[1] - '0')
+		}
+		ref = w.start(structBlock{Kind: structHeading, Level: level})
+	case "P", "Caption", "Note", "Quote", "Formula", "TOCI", "Lbl":
+		ref = w.start(structBlock{Kind: structParagraph})
+	case "Code":
+		ref = w.start(structBlock{Kind: structCode})
+	case "Table":
+		w.table(elem, page, depth, w.start(structBlock{Kind: structTable}))
+		return
+	case "L":
+		w.kids(elem, page, nil, visit(nil, list+1))
+		return
+	case "LI":
+		body := w.start(structBlock{Kind: structItem, Depth: max(list-1, 0)})
+		label := *body
+		label.label = true
+		w.kids(elem, page, body, func(kid pdf.Value) {
+			switch w.role(kid) {
+			case "Lbl":
+				w.walk(kid, page, depth+1, list, &label)
+			case "L":
+				w.walk(kid, page, depth+1, list, nil)
+			default:
+				w.walk(kid, page, depth+1, list, body)
+			}
+		})
+		return
+	default:
+		// Grouping elements such as Document, Sect and Div
+		w.kids(elem, page, nil, visit(nil, list))
+		return
+	}
+	w.kids(elem, page, ref, visit(ref, list))
+}
+
+// table visits the rows and cells of a table, including those in THead,
+// TBody and TFoot elements, numbering them in ref
+func (w *structWalker) table(elem pdf.Value, page, depth int, ref *structRef) {
+	if depth > maxStructDepth {
+		return
+	}
+	page = w.page(elem, page)
+	w.kids(elem, page, ref, func(kid pdf.Value) {
+		switch w.role(kid) {
+		case "TR":
+			ref.row++
+			ref.cell = -1
+			w.table(kid, page, depth+1, ref)
+		case "TH", "TD":
+			ref.cell++
+			cell := *ref
+			w.walk(kid, page, depth+1, 0, &cell)
+		case "THead", "TBody", "TFoot":
+			w.table(kid, page, depth+1, ref)
+		}
+	})
+}
+
+// kids visits the kids of a structure element. Marked content is added to
+// ref, or to a new paragraph when outside any block; structure elements
+// are passed to visit.
+func (w *structWalker) kids(elem pdf.Value, page int, ref *structRef, visit func(pdf.Value)) {
+	k := elem.Key("K")
+	count := 1
+	if k.Kind() == pdf.Array {
+		count = k.Len()
+	}
+
+	loose := ref
+	for i := 0; i < count; i++ {
+		kid := k
+		if k.Kind() == pdf.Array {
+			kid = k.Index(i)
+		}
+
+		mcid, kidPage := -1, page
+		switch kid.Kind() {
+		case pdf.Integer:
+			mcid = int(kid.Int64())
+		case pdf.Dict:
+			switch kid.Key("Type").Name() {
+			case "MCR":
+				// Text is only extracted from page content streams, not
+				// from form XObjects, with or without the structure tree
+				if !kid.Key("Stm").IsNull() {
+					continue
+				}
+				mcid, kidPage = int(kid.Key("MCID").Int64()), w.page(kid, page)
+			case "OBJR":
+				// Annotations and XObjects have no text
+				continue
+			default:
+				if ref == nil {
+					loose = nil
+				}
+				visit(kid)
+				continue
+			}
+		default:
+			continue
+		}
+
+		if loose == nil {
+			loose = w.start(structBlock{Kind: structParagraph})
+		}
+		w.mark(kidPage, mcid, *loose)
+	}
+}
+
+// markedContent returns the MCID of the marked-content sequence enclosing
+// each glyph in the content of a page, untaggedMCID for glyphs outside one
+// and artifactMCID for artifacts. Glyphs are counted the way page.Content
+// splits text into them.
+func markedContent(page pdf.Page) (mcids []int, ok bool) {
+	defer func() {
+		// The content stream parser panics on malformed input
+		if r := recover(); r != nil {
+			mcids, ok = nil, false
+		}
+	}()
+
+	var enc pdf.TextEncoding
+	var stack []int
+	current := func() int {
+		if len(stack) == 0 {
+			return untaggedMCID
+		}
+		return stack[len(stack)-1]
+	}
+	show := func(s string) {
+		if enc != nil {
+			s = enc.Decode(s)
+		}
+		for range s {
+			mcids = append(mcids, current())
+		}
+	}
+
+	pdf.Interpret(page.V.Key("Contents"), func(stk *pdf.Stack, op string) {
+		args := make([]pdf.Value, stk.Len())
+		for i := len(args) - 1; i >= 0; i-- {
+			args[i] = stk.Pop()
+		}
+		switch op {
+		case "BMC":
+			mcid := current()
+			if len(args) == 1 && args[0].Name() == "Artifact" {
+				mcid = artifactMCID
+			}
+			stack = append(stack, mcid)
+		case "BDC":
+			mcid := current()
+			if len(args) == 2 && args[0].Name() == "Artifact" {
+				mcid = artifactMCID
+			} else if len(args) == 2 {
+				props := args[1]
+				if props.Kind() == pdf.Name {
+					props = page.Resources().Key("Properties").Key(props.Name())
+				}
+				if id := props.Key("MCID"); id.Kind() == pdf.Integer {
+					mcid = int(id.Int64())
+				}
+			}
+			stack = append(stack, mcid)
+		case "EMC":
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case "Tf":
+			if len(args) == 2 {
+				enc = page.Font(args[0].Name()).Encoder()
+			}
+		case "Tj", "'", "\"":
+			if len(args) > 0 {
+				show(args[len(args)-1].RawString())
+			}
+		case "TJ":
+			if len(args) == 1 {
+				for i := 0; i < args[0].Len(); i++ {
+					if s := args[0].Index(i); s.Kind() == pdf.String {
+						show(s.RawString())
+					}
+				}
+				show("\n")
+			}
+		}
+	})
+	return mcids, true
+}
+
+// markedText groups the glyphs of a page by the marked content of the
+// structure tree enclosing them. Glyphs outside it, including marked content
+// no element refers to, are kept in content order as untaggedMCID; artifacts
+// are dropped. It returns nil when none of the page is in the structure tree
+// or its glyphs cannot be matched to their marked content.
+func markedText(page pdf.Page, texts []pdf.Text, refs map[int]structRef) map[int][]pdf.Text {
+	if len(refs) == 0 {
+		return nil
+	}
+	mcids, ok := markedContent(page)
+	if !ok || len(mcids) != len(texts) {
+		return nil
+	}
+	marked := make(map[int][]pdf.Text)
+	for i, mcid := range mcids {
+		if mcid == artifactMCID {
+			continue
+		}
+		if _, ok := refs[mcid]; !ok {
+			mcid = untaggedMCID
+		}
+		marked[mcid] = append(marked[mcid], texts[i])
+	}
+	if _, untagged := marked[untaggedMCID]; len(marked) == 0 || (untagged && len(marked) == 1) {
+		return nil
+	}
+	return marked
+}
+
+// markedPart is the text of one marked-content sequence
+type markedPart struct {
+	ref   structRef
+	texts []pdf.Text
+}
+
+// pageBlocks builds the blocks of a page from its marked content in the
+// logical order of the structure tree. Text outside the structure tree is
+// converted with the layout heuristics and placed by its position. It
+// reports false if none of the content of the page is tagged.
+func (tree *structTree) pageBlocks(page pageLayout, levels map[float64]int, tables string, words map[string]bool) ([]pdfBlock, bool) {
+	refs := tree.refs[page.Number]
+	parts := make(map[int][]markedPart)
+	for mcid, texts := range page.Marked {
+		if ref, ok := refs[mcid]; ok && mcid != untaggedMCID {
+			parts[ref.block] = append(parts[ref.block], markedPart{ref, texts})
+		}
+	}
+	if len(parts) == 0 {
+		return nil, false
+	}
+
+	order := make([]int, 0, len(parts))
+	for block := range parts {
+		order = append(order, block)
+	}
+	sort.Ints(order)
+
+	var blocks []pdfBlock
+	for _, i := range order {
+		block := parts[i]
+		sort.Slice(block, func(a, b int) bool { return block[a].ref.seq < block[b].ref.seq })
+		if built, ok := buildStructBlock(tree.blocks[i], block, page.Links, words); ok {
+			blocks = append(blocks, built)
+		}
+	}
+
+	var untagged []textLine
+	for _, line := range buildLines(page.Marked[untaggedMCID], page.Links) {
+		if line.Text() != "" {
+			untagged = append(untagged, line)
+		}
+	}
+	if len(untagged) > 0 {
+		layout := page
+		layout.Lines = readingOrder(untagged)
+		blocks = placeBlocks(blocks, buildBlocks(layout, levels, tables, words))
+	}
+	return placeImages(blocks, page.Lines), true
+}
+
+// placeBlocks inserts untagged blocks among the tagged blocks of a page,
+// each before the first tagged block that starts below it
+func placeBlocks(tagged, untagged []pdfBlock) []pdfBlock {
+	blocks := make([]pdfBlock, 0, len(tagged)+len(untagged))
+	i := 0
+	for _, block := range tagged {
+		for ; i < len(untagged) && untagged[i].Lines[0].Y > block.Lines[0].Y; i++ {
+			blocks = append(blocks, untagged[i])
+		}
+		blocks = append(blocks, block)
+	}
+	return append(blocks, untagged[i:]...)
+}
+
+// buildStructBlock builds a block from the marked content of a structure
+// element. It reports false if the content has no visible text.
+func buildStructBlock(sb structBlock, parts []markedPart, links []pdfLink, words map[string]bool) (pdfBlock, bool) {
+	var all, label, body []pdf.Text
+	for _, part := range parts {
+		all = append(all, part.texts...)
+		if part.ref.label {
+			label = append(label, part.texts...)
+		} else {
+			body = append(body, part.texts...)
+		}
+	}
+	lines := buildLines(all, links)
+	if len(lines) == 0 {
+		return pdfBlock{}, false
+	}
+
+	switch sb.Kind {
+	case structHeading:
+		return pdfBlock{Level: min(sb.Level, 6), Lines: lines}, true
+	case structCode:
+		return pdfBlock{Code: true, Lines: lines}, true
+	case structTable:
+		return pdfBlock{Table: structTableRows(parts, links, words), Lines: lines}, true
+	case structItem:
+		block := pdfBlock{Item: "-", Depth: sb.Depth, Lines: lines}
+		block.Runs = reflow(buildLines(body, links), words)
+		if len(label) > 0 {
+			if marker, _ := listMarker(textOf(buildLines(label, links)) + " "); marker != "" {
+				block.Item = marker
+			}
+		} else if marker, n := listMarker(textOf(lines)); marker != "" {
+			// Labels that are not tagged separately are part of the body
+			block.Item, block.Runs = marker, stripMarker(block.Runs, n)
+		}
+		return block, true
+	default:
+		return pdfBlock{Runs: reflow(lines, words), Lines: lines}, true
+	}
+}
+
+// structTableRows returns the Markdown cells of the table rows on a page
+func structTableRows(parts []markedPart, links []pdfLink, words map[string]bool) [][]string {
+	type position struct{ row, cell int }
+	cells := make(map[position][]pdf.Text)
+	var rows []int
+	cols := 0
+	for _, part := range parts {
+		pos := position{part.ref.row, part.ref.cell}
+		if pos.row < 0 || pos.cell < 0 {
+			continue
+		}
+		if len(rows) == 0 || rows[len(rows)-1] != pos.row {
+			rows = append(rows, pos.row)
+		}
+		cols = max(cols, pos.cell+1)
+		cells[pos] = append(cells[pos], part.texts...)
+	}
+
+	var table [][]string
+	seen := make(map[int]bool)
+	for _, row := range rows {
+		if seen[row] {
+			continue
+		}
+		seen[row] = true
+		out := make([]string, cols)
+		for c := range out {
+			out[c] = inlineMarkdown(reflow(buildLines(cells[position{row, c}], links), words))
+		}
+		table = append(table, out)
+	}
+	return table
+}
+
+// textOf returns the plain text of lines joined by spaces
+func textOf(lines []textLine) string {
+	texts := make([]string, len(lines))
+	for i, line := range lines {
+		texts[i] = line.Text()
+	}
+	return strings.Join(texts, " ")
+}
+
+// placeImages inserts the images among the lines of a page into blocks,
+// before the first block that starts below the top of the image
+func placeImages(blocks []pdfBlock, lines []textLine) []pdfBlock {
+	for _, line := range lines {
+		if line.Image == "" {
+			continue
+		}
+		image := pdfBlock{Image: line.Image, Lines: []textLine{line}}
+		at := len(blocks)
+		for i, block := range blocks {
+			if block.Image == "" && block.Lines[0].Y < line.Y+line.Height {
+				at = i
+				break
+			}
+		}
+		blocks = append(blocks[:at], append([]pdfBlock{image}, blocks[at:]...)...)
+	}
+	return blocks
+}
@@ -0,0 +1,178 @@
+package converter
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+// monoCharWidth is the advance of a monospace glyph relative to the font size
+const monoCharWidth = 0.6
+
+// fontStyle is the text style implied by a PDF font name
+type fontStyle struct {
+	Bold   bool
+	Italic bool
+	Mono   bool
+}
+
+var (
+	// subsetPrefixRegex matches the tag of subset fonts, e.g. "ABCDEF+Helvetica"
+	subsetPrefixRegex = regexp.MustCompile(`^[A-Z]{6}\+`)
+	// styleSuffixRegex matches style letters appended to a lower-case family
+	// name, as written by fpdf for embedded fonts (e.g. "utf8dejavuBI")
+	styleSuffixRegex = regexp.MustCompile(`[a-z0-9](B|I|BI|IB)$`)
+	// monoWordRegex matches the words of monospace font names, as in
+	// "SourceCodePro", "DejaVuSansMono-Bold" or "fira-code", but not inside
+	// "ArialUnicodeMS" or "MonotypeCorsiva". The last alternative matches
+	// the lower-case names fpdf writes, e.g. "utf8dejavumonoB".
+	monoWordRegex = regexp.MustCompile(`(?:Courier|Mono|Consolas|Menlo|Typewriter|Code)(?:[^a-z]|$|space)` +
+		`|(?:^|[^A-Za-z])(?:courier|mono|consolas|menlo|typewriter|code)(?:[^a-z]|$|space)` +
+		`|mono(?:B|I|BI|IB)?$`)
+)
+
+// styleOf derives the style of a font from its name, e.g. "Helvetica-Bold",
+// "TimesNewRoman,Italic" or "Courier"
+func styleOf(font string) fontStyle {
+	font = subsetPrefixRegex.ReplaceAllString(font, "")
+	lower := strings.ToLower(font)
+
+	var style fontStyle
+	for _, marker := range []string{"bold", "black", "heavy", "semibold", "demi"} {
+		if strings.Contains(lower, marker) {
+			style.Bold = true
+		}
+	}
+	for _, marker := range []string{"italic", "oblique"} {
+		if strings.Contains(lower, marker) {
+			style.Italic = true
+		}
+	}
+	style.Mono = monoWordRegex.MatchString(font)
+	if m := styleSuffixRegex.FindStringSubmatch(font); m != nil {
+		style.Bold = style.Bold || strings.Contains(m[1], "B")
+		style.Italic = style.Italic || strings.Contains(m[1], "I")
+	}
+	return style
+}
+
+// isCodeLine reports whether every run of a line is set in a monospace font
+func isCodeLine(line textLine) bool {
+	for _, run := range line.Runs {
+		if strings.TrimSpace(run.Text) != "" && !styleOf(run.Font).Mono {
+			return false
+		}
+	}
+	return true
+}
+
+// inlineMarkdown renders the runs of a line with emphasis, code spans and links
+func inlineMarkdown(runs []textRun) string {
+	// Merge neighbouring runs of the same style and link, e.g. runs split by size only
+	type segment struct {
+		text  string
+		style fontStyle
+		link  string
+	}
+	var segments []segment
+	for _, run := range runs {
+		style := styleOf(run.Font)
+		if n := len(segments); n > 0 && ((segments[n-1].style == style && segments[n-1].link == run.Link) || strings.TrimSpace(run.Text) == "") {
+			segments[n-1].text += run.Text
+			continue
+		}
+		segments = append(segments, segment{text: run.Text, style: style, link: run.Link})
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(segments); i++ {
+		// Consecutive segments of one link form a single link text
+		var text strings.Builder
+		link := segments[i].link
+		for ; i < len(segments) && segments[i].link == link; i++ {
+			text.WriteString(emphasize(segments[i].text, segments[i].style))
+		}
+		i--
+
+		if link == "" {
+			b.WriteString(text.String())
+			continue
+		}
+		lead, core, trail := splitSpace(text.String())
+		b.WriteString(lead + "[" + core + "](" + markdownURL(link) + ")" + trail)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// emphasize wraps the text of a run in the Markdown for its style, keeping
+// surrounding whitespace outside the markers
+func emphasize(text string, style fontStyle) string {
+	lead, core, trail := splitSpace(text)
+	if core == "" {
+		return text
+	}
+	switch {
+	case style.Mono:
+		core = codeSpan(core)
+	case style.Bold && style.Italic:
+		core = "***" + core + "***"
+	case style.Bold:
+		core = "**" + core + "**"
+	case style.Italic:
+		core = "*" + core + "*"
+	}
+	return lead + core + trail
+}
+
+// splitSpace splits text into leading whitespace, content and trailing whitespace
+func splitSpace(text string) (string, string, string) {
+	core := strings.TrimSpace(text)
+	if core == "" {
+		return text, "", ""
+	}
+	lead := text[:strings.Index(text, core)]
+	return lead, core, text[len(lead)+len(core):]
+}
+
+// codeSpan wraps text in enough backticks to contain the backticks inside it
+func codeSpan(text string) string {
+	fence := "`"
+	for strings.Contains(text, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
+
+// writeCodeBlock writes monospace lines as a fenced code block, restoring
+// indentation from the horizontal position of each line
+func writeCodeBlock(b *strings.Builder, lines []textLine) {
+	left := math.Inf(1)
+	for _, line := range lines {
+		left = math.Min(left, line.X)
+	}
+
+	fence := "```"
+	for _, line := range lines {
+		for strings.Contains(line.Text(), fence) {
+			fence += "`"
+		}
+	}
+
+	b.WriteString(fence + "\n")
+	for _, line := range lines {
+		indent := 0
+		if line.Size > 0 {
+			indent = int(math.Round((line.X - left) / (line.Size * monoCharWidth)))
+		}
+		// Leading spaces drawn as glyphs are kept as well
+		var text strings.Builder
+		for _, run := range line.Runs {
+			text.WriteString(run.Text)
+		}
+		b.WriteString(strings.Repeat(" ", indent) + strings.TrimRight(text.String(), " ") + "\n")
+	}
+	b.WriteString(fence + "\n")
+}
@@ -0,0 +1,223 @@
+package converter
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// Table detection modes of the "tables" option
+const (
+	tablesOff        = "off"
+	tablesAuto       = "auto"
+	tablesAggressive = "aggressive"
+)
+
+// Table heuristics, relative to the font size of the text involved
+const (
+	cellGapAuto       = 2.0 // horizontal gap between cells in auto mode
+	cellGapAggressive = 1.0 // horizontal gap between cells in aggressive mode
+	rowGapRatio       = 3.0 // largest baseline distance between two rows
+	rulingThickness   = 2.0 // rectangles thinner than this (in points) are lines
+)
+
+// pdfTable is a table found in the lines of a page
+type pdfTable struct {
+	end  int // index of the first line after the table
+	rows [][]string
+}
+
+// textCell is a group of runs of a line separated from its neighbours by a gap or a ruling
+type textCell struct {
+	runs  []textRun
+	x     float64
+	right float64
+}
+
+// findTables returns the tables of a page keyed by the index of their first line
+func findTables(page pageLayout, mode string) map[int]pdfTable {
+	tables := make(map[int]pdfTable)
+	if mode == tablesOff {
+		return tables
+	}
+
+	gapRatio := cellGapAuto
+	if mode == tablesAggressive {
+		gapRatio = cellGapAggressive
+	}
+
+	cells := make([][]textCell, len(page.Lines))
+	for i, line := range page.Lines {
+		cells[i] = lineCells(line, rulingsAt(page.Rects, line), gapRatio)
+	}
+
+	for start := 0; start < len(page.Lines); start++ {
+		if len(cells[start]) < 2 {
+			continue
+		}
+
+		// Extend the candidate over following rows of several cells
+		end := start + 1
+		for end < len(page.Lines) {
+			prev, line := page.Lines[end-1], page.Lines[end]
+			if prev.Y-line.Y > prev.Size*rowGapRatio || prev.Y <= line.Y {
+				break
+			}
+			if len(cells[end]) < 2 && (mode != tablesAggressive || !continuesRow(cells[end], cells[end-1])) {
+				break
+			}
+			end++
+		}
+		if end-start < 2 {
+			continue
+		}
+
+		rows, ok := alignColumns(cells[start:end], mode == tablesAggressive)
+		if !ok {
+			continue
+		}
+		tables[start] = pdfTable{end: end, rows: rows}
+		start = end - 1
+	}
+	return tables
+}
+
+// rulingsAt returns the X positions of vertical ruling lines and cell
+// borders that cross the baseline of a line
+func rulingsAt(rects []pdf.Rect, line textLine) []float64 {
+	var xs []float64
+	for _, r := range rects {
+		minX, maxX := math.Min(r.Min.X, r.Max.X), math.Max(r.Min.X, r.Max.X)
+		minY, maxY := math.Min(r.Min.Y, r.Max.Y), math.Max(r.Min.Y, r.Max.Y)
+		if line.Y < minY || line.Y > maxY {
+			continue
+		}
+		if maxX-minX < rulingThickness {
+			xs = append(xs, (minX+maxX)/2)
+		} else if maxY-minY >= rulingThickness {
+			xs = append(xs, minX, maxX)
+		}
+	}
+	return xs
+}
+
+// lineCells splits a line into cells at wide gaps and at vertical rulings
+func lineCells(line textLine, rulings []float64, gapRatio float64) []textCell {
+	var cells []textCell
+	for _, run := range line.Runs {
+		if strings.TrimSpace(run.Text) == "" {
+			continue
+		}
+		if n := len(cells); n > 0 {
+			last := &cells[n-1]
+			start := last.runs[len(last.runs)-1].X
+			if run.X-last.right <= run.Size*gapRatio && !rulingBetween(rulings, start, run.X) {
+				last.runs = append(last.runs, run)
+				last.right = math.Max(last.right, run.Right)
+				continue
+			}
+		}
+		cells = append(cells, textCell{runs: []textRun{run}, x: run.X, right: run.Right})
+	}
+	return cells
+}
+
+// rulingBetween reports whether a ruling lies between the starts of two runs.
+// Estimated glyph widths may overshoot a ruling, so the end of the first run is not used.
+func rulingBetween(rulings []float64, left, right float64) bool {
+	for _, x := range rulings {
+		if x > left && x <= right {
+			return true
+		}
+	}
+	return false
+}
+
+// continuesRow reports whether a single-cell line is a wrapped part of a table
+// row, i.e. it starts to the right of the first cell of the row above
+func continuesRow(cells, above []textCell) bool {
+	return len(cells) == 1 && len(above) > 1 && cells[0].x > above[0].right
+}
+
+// alignColumns assigns the cells of each row to columns found by merging
+// overlapping cell extents across rows. Outside aggressive mode a row with
+// two cells in the same column rejects the table.
+func alignColumns(rows [][]textCell, aggressive bool) ([][]string, bool) {
+	type span struct{ x, right float64 }
+	var spans []span
+	for _, row := range rows {
+		for _, cell := range row {
+			spans = append(spans, span{cell.x, cell.right})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].x < spans[j].x })
+
+	var columns []span
+	for _, s := range spans {
+		if n := len(columns); n > 0 && s.x < columns[n-1].right {
+			columns[n-1].right = math.Max(columns[n-1].right, s.right)
+			continue
+		}
+		columns = append(columns, s)
+	}
+	if len(columns) < 2 {
+		return nil, false
+	}
+
+	var table [][]string
+	for _, row := range rows {
+		out := make([]string, len(columns))
+		for _, cell := range row {
+			col := 0
+			for col < len(columns)-1 && cell.x >= columns[col+1].x {
+				col++
+			}
+			text := inlineMarkdown(cell.runs)
+			if out[col] != "" {
+				if !aggressive {
+					return nil, false
+				}
+				text = out[col] + " " + text
+			}
+			out[col] = text
+		}
+		table = append(table, out)
+	}
+
+	// Wrapped lines continue the cells of the row above
+	merged := [][]string{table[0]}
+	for i, row := range table[1:] {
+		if aggressive && len(rows[i+1]) == 1 && row[0] == "" {
+			last := merged[len(merged)-1]
+			for c, text := range row {
+				if text != "" {
+					last[c] = strings.TrimSpace(last[c] + " " + text)
+				}
+			}
+			continue
+		}
+		merged = append(merged, row)
+	}
+	return merged, len(merged) >= 2
+}
+
+// writeTable writes rows as a GFM pipe table with the first row as header
+func writeTable(b *strings.Builder, rows [][]string) {
+	for i, row := range rows {
+		cells := make([]string, len(row))
+		for c, text := range row {
+			cells[c] = strings.ReplaceAll(text, "|", `\|`)
+		}
+		b.WriteString("| " + strings.Join(cells, " | ") + " |\n")
+
+		if i == 0 {
+			separators := make([]string, len(row))
+			for c := range separators {
+				separators[c] = "---"
+			}
+			b.WriteString("| " + strings.Join(separators, " | ") + " |\n")
+		}
+	}
+}
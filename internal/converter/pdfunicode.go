@@ -0,0 +1,419 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// toUnicodeMap is the ToUnicode CMap of a font. The PDF library adds the
+// offset of a code into a bfrange to the last byte of the destination only,
+// so ranges spanning more than the last byte, such as the identity range
+// <0000> <FFFF> <0000> fpdf writes for Unicode fonts, lose the high byte:
+// "•" (U+2022) is read as "\"" (U+0022).
+type toUnicodeMap struct {
+	space  [4][]codeRange // by code length
+	chars  map[string]string
+	ranges []cmapRange
+}
+
+// codeRange is a range of character codes of the same length
+type codeRange struct {
+	lo, hi string
+}
+
+// cmapRange maps a range of codes to consecutive UTF-16 strings starting at
+// dst, or to the strings of an array
+type cmapRange struct {
+	codeRange
+	dst   string
+	array []string
+}
+
+// readToUnicode parses the ToUnicode CMap of a font. It returns nil if the
+// font has none, or if the PDF library decodes it correctly.
+func readToUnicode(font pdf.Font) (m *toUnicodeMap) {
+	stream := font.V.Key("ToUnicode")
+	if stream.Kind() != pdf.Stream {
+		return nil
+	}
+	defer func() {
+		// The stream reader panics on malformed input
+		if r := recover(); r != nil {
+			m = nil
+		}
+	}()
+	data, err := io.ReadAll(stream.Reader())
+	if err != nil {
+		return nil
+	}
+
+	m = parseToUnicode(data)
+	for _, r := range m.ranges {
+		if r.misread() {
+			return m
+		}
+	}
+	return nil
+}
+
+// parseToUnicode reads the code space ranges and mappings of a ToUnicode CMap
+func parseToUnicode(data []byte) *toUnicodeMap {
+	m := &toUnicodeMap{chars: make(map[string]string)}
+	var operands []cmapToken
+	for _, token := range cmapTokens(data) {
+		if token.keyword == "" {
+			operands = append(operands, token)
+			continue
+		}
+		switch token.keyword {
+		case "endcodespacerange":
+			for i := 0; i+1 < len(operands); i += 2 {
+				lo, hi := operands[i].text, operands[i+1].text
+				if len(lo) > 0 && len(lo) == len(hi) && len(lo) <= 4 {
+					m.space[len(lo)-1] = append(m.space[len(lo)-1], codeRange{lo, hi})
+				}
+			}
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				m.chars[operands[i].text] = operands[i+1].text
+			}
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, hi := operands[i].text, operands[i+1].text
+				if len(lo) > 0 && len(lo) == len(hi) {
+					m.ranges = append(m.ranges, cmapRange{codeRange{lo, hi}, operands[i+2].text, operands[i+2].array})
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+	return m
+}
+
+// cmapToken is a string, an array of strings or a keyword of a CMap.
+// Numbers and names are kept as keywords as only strings are operands of
+// the mappings.
+type cmapToken struct {
+	text    string
+	array   []string
+	keyword string
+}
+
+// cmapTokens splits a CMap into tokens
+func cmapTokens(data []byte) []cmapToken {
+	var tokens []cmapToken
+	var array []string
+	inArray := false
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '%':
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+		case c == '<' && i+1 < len(data) && data[i+1] == '<':
+			// Dictionaries only hold CMap properties
+			i += 2
+		case c == '>':
+			i++
+		case c == '<':
+			end := bytes.IndexByte(data[i:], '>')
+			if end < 0 {
+				return tokens
+			}
+			s := hexString(data[i+1 : i+end])
+			i += end + 1
+			if inArray {
+				array = append(array, s)
+			} else {
+				tokens = append(tokens, cmapToken{text: s})
+			}
+		case c == '(':
+			s, n := literalString(data[i:])
+			i += n
+			if inArray {
+				array = append(array, s)
+			} else {
+				tokens = append(tokens, cmapToken{text: s})
+			}
+		case c == '[':
+			inArray, array = true, nil
+			i++
+		case c == ']':
+			inArray = false
+			tokens = append(tokens, cmapToken{array: array})
+			i++
+		case isPDFSpace(c):
+			i++
+		default:
+			start := i
+			for i < len(data) && !isPDFSpace(data[i]) && !strings.ContainsRune("<>[]()%", rune(data[i])) {
+				i++
+			}
+			if i == start {
+				i++
+				continue
+			}
+			tokens = append(tokens, cmapToken{keyword: string(data[start:i])})
+		}
+	}
+	return tokens
+}
+
+// isPDFSpace reports whether c is white space in PDF syntax
+func isPDFSpace(c byte) bool {
+	return strings.IndexByte(" \t\r\n\f\x00", c) >= 0
+}
+
+// hexString decodes a hexadecimal string, ignoring white space and padding
+// an odd number of digits with 0
+func hexString(digits []byte) string {
+	var clean []byte
+	for _, c := range digits {
+		if !isPDFSpace(c) {
+			clean = append(clean, c)
+		}
+	}
+	if len(clean)%2 == 1 {
+		clean = append(clean, '0')
+	}
+	s, err := hex.DecodeString(string(clean))
+	if err != nil {
+		return ""
+	}
+	return string(s)
+}
+
+// literalString decodes a literal string at the start of data and returns
+// it with the number of bytes read
+func literalString(data []byte) (string, int) {
+	var s []byte
+	depth := 0
+	for i := 0; i < len(data); i++ {
+		switch c := data[i]; c {
+		case '(':
+			if depth > 0 {
+				s = append(s, c)
+			}
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return string(s), i + 1
+			}
+			s = append(s, c)
+		case '\\':
+			if i++; i < len(data) {
+				switch e := data[i]; e {
+				case 'n':
+					s = append(s, '\n')
+				case 'r':
+					s = append(s, '\r')
+				case 't':
+					s = append(s, '\t')
+				case 'b':
+					s = append(s, '\b')
+				case 'f':
+					s = append(s, '\f')
+				default:
+					if e >= '0' && e <= '7' {
+						v := 0
+						for n := 0; n < 3 && i < len(data) && data[i] >= '0' && data[i] <= '7'; n++ {
+							v = v*8 + int(data[i]-'0')
+							i++
+						}
+						i--
+						s = append(s, byte(v))
+					} else {
+						s = append(s, e)
+					}
+				}
+			}
+		default:
+			s = append(s, c)
+		}
+	}
+	return string(s), len(data)
+}
+
+// misread reports whether the PDF library decodes the range wrongly
+func (r cmapRange) misread() bool {
+	last := len(r.lo) - 1
+	if r.lo[:last] != r.hi[:last] {
+		return true
+	}
+	return r.array == nil && r.dst != "" &&
+		int(r.dst[len(r.dst)-1])+int(r.hi[last])-int(r.lo[last]) > 0xFF
+}
+
+// Decode returns the text of a string of character codes, one rune per code
+// like the PDF library
+func (m *toUnicodeMap) Decode(raw string) string {
+	var text []rune
+	for len(raw) > 0 {
+		n := m.codeLength(raw)
+		if n == 0 {
+			text = append(text, unicode.ReplacementChar)
+			raw = raw[1:]
+			continue
+		}
+		code := raw[:n]
+		raw = raw[n:]
+		text = append(text, m.decodeCode(code)...)
+	}
+	return string(text)
+}
+
+// codeLength returns the length of the code at the start of raw, or 0 if it
+// is in no code space range
+func (m *toUnicodeMap) codeLength(raw string) int {
+	for n := 1; n <= 4 && n <= len(raw); n++ {
+		for _, space := range m.space[n-1] {
+			if space.lo <= raw[:n] && raw[:n] <= space.hi {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// decodeCode returns the text of a single character code
+func (m *toUnicodeMap) decodeCode(code string) []rune {
+	if dst, ok := m.chars[code]; ok {
+		return utf16Runes(dst)
+	}
+	for _, r := range m.ranges {
+		if len(r.lo) != len(code) || code < r.lo || code > r.hi {
+			continue
+		}
+		offset := codeValue(code) - codeValue(r.lo)
+		if r.array != nil {
+			if offset < len(r.array) {
+				return utf16Runes(r.array[offset])
+			}
+			break
+		}
+		dst := []byte(r.dst)
+		// Add the offset to the destination as a big-endian number
+		for i := len(dst) - 1; i >= 0 && offset > 0; i-- {
+			sum := int(dst[i]) + offset
+			dst[i] = byte(sum)
+			offset = sum >> 8
+		}
+		return utf16Runes(string(dst))
+	}
+	return []rune{unicode.ReplacementChar}
+}
+
+// codeValue returns a character code as a number
+func codeValue(code string) int {
+	v := 0
+	for i := 0; i < len(code); i++ {
+		v = v<<8 | int(code[i])
+	}
+	return v
+}
+
+// utf16Runes decodes a big-endian UTF-16 string
+func utf16Runes(s string) []rune {
+	units := make([]uint16, 0, len(s)/2)
+	for i := 0; i+1 < len(s); i += 2 {
+		units = append(units, uint16(s[i])<<8|uint16(s[i+1]))
+	}
+	return utf16.Decode(units)
+}
+
+// fixUnicode replaces the text of glyphs the PDF library misread through the
+// ToUnicode CMap of their font. The content stream is interpreted again with
+// the correct decoding and matched to the glyphs the way page.Content splits
+// text into them; if it does not match, the glyphs are returned unchanged.
+func fixUnicode(page pdf.Page, texts []pdf.Text) []pdf.Text {
+	fonts := make(map[string]*toUnicodeMap)
+	for _, name := range page.Fonts() {
+		if m := readToUnicode(page.Font(name)); m != nil {
+			fonts[name] = m
+		}
+	}
+	if len(fonts) == 0 {
+		return texts
+	}
+
+	fixed, ok := decodeText(page, fonts, texts)
+	if !ok {
+		return texts
+	}
+	return fixed
+}
+
+// decodeText decodes the text shown on a page with the fonts whose CMaps
+// the PDF library misreads and puts it in a copy of texts
+func decodeText(page pdf.Page, fonts map[string]*toUnicodeMap, texts []pdf.Text) (fixed []pdf.Text, ok bool) {
+	defer func() {
+		// The content stream parser panics on malformed input
+		if r := recover(); r != nil {
+			fixed, ok = nil, false
+		}
+	}()
+
+	fixed = append([]pdf.Text(nil), texts...)
+	var enc pdf.TextEncoding
+	var cmap *toUnicodeMap
+	i := 0
+	ok = true
+	show := func(s string) {
+		misread := []rune(s)
+		if enc != nil {
+			misread = []rune(enc.Decode(s))
+		}
+		var decoded []rune
+		if cmap != nil {
+			decoded = []rune(cmap.Decode(s))
+		}
+		for k, ch := range misread {
+			if i >= len(fixed) || fixed[i].S != string(ch) {
+				ok = false
+				return
+			}
+			if len(decoded) == len(misread) {
+				fixed[i].S = string(decoded[k])
+			}
+			i++
+		}
+	}
+
+	pdf.Interpret(page.V.Key("Contents"), func(stk *pdf.Stack, op string) {
+		args := make([]pdf.Value, stk.Len())
+		for i := len(args) - 1; i >= 0; i-- {
+			args[i] = stk.Pop()
+		}
+		if !ok {
+			return
+		}
+		switch op {
+		case "Tf":
+			if len(args) == 2 {
+				enc = page.Font(args[0].Name()).Encoder()
+				cmap = fonts[args[0].Name()]
+			}
+		case "Tj", "'", "\"":
+			if len(args) > 0 {
+				show(args[len(args)-1].RawString())
+			}
+		case "TJ":
+			if len(args) == 1 {
+				for i := 0; i < args[0].Len(); i++ {
+					if s := args[0].Index(i); s.Kind() == pdf.String {
+						show(s.RawString())
+					}
+				}
+				show("\n")
+			}
+		}
+	})
+	return fixed, ok && i == len(fixed)
+}
@@ -0,0 +1,259 @@
+package converter
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/green-creeper/mdtool/pkg/models"
+	"github.com/yuin/goldmark/ast"
+	extast "github.com/yuin/goldmark/extension/ast"
+)
+
+// updateBaselines rewrites the recorded round-trip scores instead of
+// checking against them:
+//
+//	go test ./internal/converter -run TestRoundTrip -update-baselines
+var updateBaselines = flag.Bool("update-baselines", false, "record the current round-trip scores as baselines")
+
+const (
+	roundTripDir       = "testdata/roundtrip"
+	roundTripBaselines = "testdata/roundtrip/baselines.json"
+)
+
+// roundTripScores are the similarities, from 0 to 1, of the structure of a
+// fixture and its round trip through md2pdf and pdf2md
+type roundTripScores struct {
+	Headings float64 `json:"headings"`
+	Lists    float64 `json:"lists"`
+	Tables   float64 `json:"tables"`
+	Code     float64 `json:"code"`
+	Text     float64 `json:"text"`
+}
+
+// markdownStructure is what a Markdown document is scored on
+type markdownStructure struct {
+	headings []string // level and text, e.g. "2 Requirements"
+	items    []string
+	cells    []string // row, column and text of table cells
+	code     []string // lines of code blocks
+	words    []string // lower-cased words of all text
+}
+
+func TestRoundTrip(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join(roundTripDir, "*.md"))
+	if err != nil || len(fixtures) == 0 {
+		t.Fatalf("No round-trip fixtures found: %v", err)
+	}
+
+	baselines := make(map[string]roundTripScores)
+	if data, err := os.ReadFile(roundTripBaselines); err == nil {
+		if err := json.Unmarshal(data, &baselines); err != nil {
+			t.Fatalf("Failed to parse %s: %v", roundTripBaselines, err)
+		}
+	} else if !*updateBaselines {
+		t.Fatalf("Failed to read baselines: %v", err)
+	}
+
+	recorded := make(map[string]roundTripScores)
+	for _, fixture := range fixtures {
+		name := filepath.Base(fixture)
+		t.Run(strings.TrimSuffix(name, ".md"), func(t *testing.T) {
+			source, err := os.ReadFile(fixture)
+			if err != nil {
+				t.Fatal(err)
+			}
+			scores := scoreRoundTrip(source, roundTrip(t, source))
+			t.Logf("%s: %+v", name, scores)
+			recorded[name] = floorScores(scores)
+
+			baseline, ok := baselines[name]
+			if *updateBaselines {
+				return
+			}
+			if !ok {
+				t.Fatalf("No baseline recorded for %s, run with -update-baselines", name)
+			}
+			check := func(aspect string, score, want float64) {
+				if score < want {
+					t.Errorf("%s score dropped to %.2f, baseline %.2f", aspect, score, want)
+				} else if score >= want+0.01 {
+					t.Logf("%s score improved to %.2f, baseline %.2f; consider updating the baselines", aspect, score, want)
+				}
+			}
+			check("headings", scores.Headings, baseline.Headings)
+			check("lists", scores.Lists, baseline.Lists)
+			check("tables", scores.Tables, baseline.Tables)
+			check("code", scores.Code, baseline.Code)
+			check("text", scores.Text, baseline.Text)
+		})
+	}
+
+	if *updateBaselines {
+		data, err := json.MarshalIndent(recorded, "", "  ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(roundTripBaselines, append(data, '\n'), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// roundTrip renders Markdown to PDF and extracts Markdown from it again
+func roundTrip(t *testing.T, source []byte) []byte {
+	t.Helper()
+
+	var pdfData bytes.Buffer
+	resp := NewMD2PDFConverter().Convert(&models.ConvertRequest{Input: bytes.NewReader(source), Output: &pdfData})
+	if !resp.Success {
+		t.Fatalf("md2pdf failed: %v", resp.Error)
+	}
+
+	var markdown bytes.Buffer
+	resp = NewPDF2MDConverter().Convert(&models.ConvertRequest{
+		Input:   bytes.NewReader(pdfData.Bytes()),
+		Output:  &markdown,
+		Options: map[string]interface{}{"continuous": true, "front_matter": false},
+	})
+	if !resp.Success {
+		t.Fatalf("pdf2md failed: %v", resp.Error)
+	}
+	return markdown.Bytes()
+}
+
+// scoreRoundTrip compares the structure of the original Markdown with the
+// Markdown extracted after the round trip
+func scoreRoundTrip(original, extracted []byte) roundTripScores {
+	want, got := structureOf(original), structureOf(extracted)
+	return roundTripScores{
+		Headings: similarity(want.headings, got.headings),
+		Lists:    similarity(want.items, got.items),
+		Tables:   similarity(want.cells, got.cells),
+		Code:     similarity(want.code, got.code),
+		Text:     similarity(want.words, got.words),
+	}
+}
+
+// structureOf collects the headings, list items, table cells, code lines
+// and words of a Markdown document
+func structureOf(source []byte) markdownStructure {
+	var s markdownStructure
+	_ = ast.Walk(parseMarkdown(source), func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch node := n.(type) {
+		case *ast.Heading:
+			s.headings = append(s.headings, fmt.Sprintf("%d %s", node.Level, plainText(node, source)))
+		case *ast.ListItem:
+			// The text of an item without the items nested in it
+			var parts []string
+			for child := node.FirstChild(); child != nil; child = child.NextSibling() {
+				if _, nested := child.(*ast.List); !nested {
+					parts = append(parts, plainText(child, source))
+				}
+			}
+			s.items = append(s.items, strings.Join(parts, " "))
+		case *extast.TableCell:
+			row, col := 0, 0
+			for c := node.PreviousSibling(); c != nil; c = c.PreviousSibling() {
+				col++
+			}
+			for r := node.Parent().PreviousSibling(); r != nil; r = r.PreviousSibling() {
+				row++
+			}
+			s.cells = append(s.cells, fmt.Sprintf("%d,%d %s", row, col, plainText(node, source)))
+		case *ast.FencedCodeBlock, *ast.CodeBlock:
+			lines := n.Lines()
+			for i := 0; i < lines.Len(); i++ {
+				line := lines.At(i)
+				text := strings.TrimRight(string(line.Value(source)), "\n")
+				s.code = append(s.code, text)
+				s.words = append(s.words, strings.Fields(strings.ToLower(text))...)
+			}
+		case *ast.Text:
+			s.words = append(s.words, strings.Fields(strings.ToLower(string(node.Segment.Value(source))))...)
+		}
+		return ast.WalkContinue, nil
+	})
+	return s
+}
+
+// plainText returns the text of a node without inline formatting
+func plainText(n ast.Node, source []byte) string {
+	var b strings.Builder
+	_ = ast.Walk(n, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if text, ok := n.(*ast.Text); ok && entering {
+			b.Write(text.Segment.Value(source))
+			if text.SoftLineBreak() {
+				b.WriteString(" ")
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// similarity scores two sequences by the length of their longest common
+// subsequence relative to their lengths, 1 for identical sequences
+func similarity(a, b []string) float64 {
+	if len(a) == 0 && len(b) == 0 {
+		return 1
+	}
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return 2 * float64(prev[len(b)]) / float64(len(a)+len(b))
+}
+
+// floorScores rounds scores down to hundredths for recording as baselines,
+// so that the scores they were recorded from pass
+func floorScores(s roundTripScores) roundTripScores {
+	floor := func(v float64) float64 { return math.Floor(v*100) / 100 }
+	return roundTripScores{floor(s.Headings), floor(s.Lists), floor(s.Tables), floor(s.Code), floor(s.Text)}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b []string
+		want float64
+	}{
+		{nil, nil, 1},
+		{[]string{"a", "b"}, []string{"a", "b"}, 1},
+		{[]string{"a", "b"}, nil, 0},
+		{[]string{"a", "b", "c", "d"}, []string{"a", "c"}, 2 * 2.0 / 6},
+		{[]string{"a", "b"}, []string{"b", "a"}, 0.5},
+	}
+	for _, tt := range tests {
+		if got := similarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("similarity(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	// Headings, items and cells are compared with their level and position
+	s := structureOf([]byte("# Title\n\n- one\n- two\n\n| A | B |\n| --- | --- |\n| 1 | 2 |\n"))
+	got := strings.Join(append(append(s.headings, s.items...), s.cells...), ";")
+	if want := "1 Title;one;two;0,0 A;0,1 B;1,0 1;1,1 2"; got != want {
+		t.Errorf("structureOf() = %q, want %q", got, want)
+	}
+	sort.Strings(s.words)
+	if got := strings.Join(s.words, " "); got != "1 2 a b one title two" {
+		t.Errorf("structureOf() words = %q", got)
+	}
+}
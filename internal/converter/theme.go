@@ -0,0 +1,111 @@
+package converter
+
+import (
+	"sort"
+
+	"codeberg.org/go-pdf/fpdf"
+)
+
+// rgb is a color with 0-255 components as used by fpdf
+type rgb struct {
+	R, G, B int
+}
+
+// theme describes the colors and type sizes of a slide deck and the
+// heading classes of a document
+type theme struct {
+	Background     rgb
+	Text           rgb
+	Title          rgb
+	Accent         rgb
+	CodeBackground rgb
+	CodeText       rgb
+	TitleSize      float64 // in points
+	BodySize       float64 // in points
+}
+
+// themes are the built-in themes, selected with --theme or, in slide decks, <!-- theme: name -->
+var themes = map[string]theme{
+	"light": {
+		Background:     rgb{255, 255, 255},
+		Text:           rgb{40, 40, 40},
+		Title:          rgb{20, 20, 20},
+		Accent:         rgb{0, 102, 204},
+		CodeBackground: rgb{245, 245, 245},
+		CodeText:       rgb{40, 40, 40},
+		TitleSize:      32,
+		BodySize:       20,
+	},
+	"dark": {
+		Background:     rgb{30, 30, 36},
+		Text:           rgb{220, 220, 220},
+		Title:          rgb{255, 255, 255},
+		Accent:         rgb{255, 166, 0},
+		CodeBackground: rgb{50, 50, 60},
+		CodeText:       rgb{230, 230, 230},
+		TitleSize:      32,
+		BodySize:       20,
+	},
+	"solarized": {
+		Background:     rgb{253, 246, 227},
+		Text:           rgb{101, 123, 131},
+		Title:          rgb{7, 54, 66},
+		Accent:         rgb{203, 75, 22},
+		CodeBackground: rgb{238, 232, 213},
+		CodeText:       rgb{88, 110, 117},
+		TitleSize:      32,
+		BodySize:       20,
+	},
+	"contrast": {
+		Background:     rgb{0, 0, 0},
+		Text:           rgb{255, 255, 255},
+		Title:          rgb{255, 255, 0},
+		Accent:         rgb{0, 255, 255},
+		CodeBackground: rgb{40, 40, 40},
+		CodeText:       rgb{255, 255, 255},
+		TitleSize:      36,
+		BodySize:       24,
+	},
+}
+
+// themeNames returns the names of the built-in themes in sorted order
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// headingStyle is the style of headings carrying a class attribute, e.g. ## Title {.accent}
+type headingStyle struct {
+	Color     *rgb    // text color, nil keeps the default
+	Align     string  // fpdf alignment "L", "C" or "R", empty keeps the default
+	Scale     float64 // multiplier for the heading size, 0 keeps it
+	PageBreak bool    // start the heading on a new page
+}
+
+// headingStyles returns the heading classes of the theme
+func (t theme) headingStyles() map[string]headingStyle {
+	muted := rgb{128, 128, 128}
+	return map[string]headingStyle{
+		"accent":  {Color: &t.Accent},
+		"muted":   {Color: &muted},
+		"center":  {Align: "C"},
+		"right":   {Align: "R"},
+		"large":   {Scale: 1.25},
+		"small":   {Scale: 0.85},
+		"chapter": {Scale: 1.25, PageBreak: true},
+	}
+}
+
+// setTextColor sets the text color of pdf to c
+func setTextColor(pdf *fpdf.Fpdf, c rgb) {
+	pdf.SetTextColor(c.R, c.G, c.B)
+}
+
+// setFillColor sets the fill color of pdf to c
+func setFillColor(pdf *fpdf.Fpdf, c rgb) {
+	pdf.SetFillColor(c.R, c.G, c.B)
+}
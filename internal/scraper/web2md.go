@@ -1,18 +1,16 @@
 package scraper
 
 import (
-	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
-	"strings"
 	"time"
 
 	md "github.com/JohannesKaufmann/html-to-markdown"
 	"github.com/JohannesKaufmann/html-to-markdown/plugin"
-	readability "github.com/go-shiori/go-readability"
+	"github.com/green-creeper/mdtool/internal/converter"
 	"github.com/green-creeper/mdtool/pkg/models"
 )
 
@@ -68,12 +66,12 @@ func (c *Web2MDConverter) Convert(req *models.ConvertRequest) *models.ConvertRes
 		}
 	}
 
-	// Parse with readability to extract main content
-	article, err := readability.FromReader(bytes.NewReader(bodyBytes), parsedURL)
+	// Extract the main content, the same way html2md --readability does
+	article, err := converter.ExtractArticle(bodyBytes, parsedURL, req.StringOption("select", ""), req.StringOption("exclude", ""))
 	if err != nil {
 		return &models.ConvertResponse{
 			Success: false,
-			Error:   fmt.Errorf("failed to parse article content: %w", err),
+			Error:   err,
 		}
 	}
 
@@ -87,36 +85,7 @@ func (c *Web2MDConverter) Convert(req *models.ConvertRequest) *models.ConvertRes
 	}
 
 	// Add article metadata as header
-	var builder strings.Builder
-	// Calculate total size:
-	// "# " (2) + title + "\n\n" (2)
-	// optional: "*By " (4) + byline + "*\n\n" (3)
-	// "*Source: [" (10) + url + "](" (2) + url + ")*\n\n---\n\n" (11)
-	// markdown
-	size := 2 + len(article.Title) + 2 + 10 + len(urlStr) + 2 + len(urlStr) + 11 + len(markdown)
-	if article.Byline != "" {
-		size += 4 + len(article.Byline) + 3
-	}
-	builder.Grow(size)
-
-	builder.WriteString("# ")
-	builder.WriteString(article.Title)
-	builder.WriteString("\n\n")
-
-	if article.Byline != "" {
-		builder.WriteString("*By ")
-		builder.WriteString(article.Byline)
-		builder.WriteString("*\n\n")
-	}
-
-	builder.WriteString("*Source: [")
-	builder.WriteString(urlStr)
-	builder.WriteString("](")
-	builder.WriteString(urlStr)
-	builder.WriteString(")*\n\n---\n\n")
-	builder.WriteString(markdown)
-
-	fullMarkdown := builder.String()
+	fullMarkdown := converter.ArticleMarkdown(article, urlStr, markdown)
 
 	// Write output
 	_, err = req.Output.Write([]byte(fullMarkdown))
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/green-creeper/mdtool/internal/converter"
 	"github.com/green-creeper/mdtool/pkg/models"
 )
 
@@ -56,6 +57,76 @@ func TestWeb2MDConverter_Convert(t *testing.T) {
 	}
 }
 
+func TestWeb2MDConverter_SelectExclude(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, `<html><head><title>Recipe</title></head><body>
+<nav><a href="/">Home</a> <a href="/recipes">Recipes</a></nav>
+<main><article><h1>Lentil Soup</h1><p>A hearty soup for cold evenings, made with red lentils, carrots and cumin.</p>
+<p>Serves four and keeps well in the fridge for up to three days.</p><div class="ads">Buy our cookbook</div></article>
+<section class="notes"><p>See <a href="/tips">tips</a>.</p></section></main>
+<footer>Copyright</footer></body></html>`)
+	}))
+	defer ts.Close()
+
+	c := NewWeb2MDConverter()
+	convert := func(options map[string]interface{}) string {
+		options["url"] = ts.URL
+		var output bytes.Buffer
+		resp := c.Convert(&models.ConvertRequest{Output: &output, Options: options})
+		if !resp.Success {
+			t.Fatalf("Convert() failed: %v", resp.Error)
+		}
+		return output.String()
+	}
+
+	result := convert(map[string]interface{}{"exclude": ".ads, nav, footer"})
+	if strings.Contains(result, "Buy our cookbook") || !strings.Contains(result, "Lentil Soup") {
+		t.Errorf("Expected the article without excluded elements, got:\n%s", result)
+	}
+
+	// Selected elements replace the block readability picks, with absolute links
+	result = convert(map[string]interface{}{"select": ".notes"})
+	if !strings.Contains(result, "# Recipe") || !strings.Contains(result, "See [tips]("+ts.URL+"/tips).") {
+		t.Errorf("Expected the title and the selected notes, got:\n%s", result)
+	}
+	if strings.Contains(result, "hearty soup") {
+		t.Errorf("Expected only the selected elements, got:\n%s", result)
+	}
+}
+
+func TestWeb2MDConverter_SameAsHTML2MD(t *testing.T) {
+	page := `<html><head><title>Recipe</title><meta name="author" content="Ada Cook"></head><body>
+<nav><a href="/">Home</a></nav><main><article><h1>Lentil Soup</h1>
+<p>A hearty soup for cold evenings, made with red lentils, carrots and cumin. See <a href="/tips">tips</a>.</p>
+<p>Serves four and keeps well in the fridge for up to three days.</p></article></main></body></html>`
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, page)
+	}))
+	defer ts.Close()
+
+	// A saved copy of the page converted with html2md --readability gives
+	// the same Markdown as web2md
+	var web, local bytes.Buffer
+	resp := NewWeb2MDConverter().Convert(&models.ConvertRequest{Output: &web, Options: map[string]interface{}{"url": ts.URL}})
+	if !resp.Success {
+		t.Fatalf("web2md failed: %v", resp.Error)
+	}
+	resp = converter.NewHTML2MDConverter().Convert(&models.ConvertRequest{
+		Input:   strings.NewReader(page),
+		Output:  &local,
+		Options: map[string]interface{}{"readability": true, "base_url": ts.URL},
+	})
+	if !resp.Success {
+		t.Fatalf("html2md failed: %v", resp.Error)
+	}
+	if web.String() != local.String() {
+		t.Errorf("Expected the same Markdown from web2md and html2md, got:\n%s\nand:\n%s", web.String(), local.String())
+	}
+	if !strings.Contains(local.String(), "*By Ada Cook*") || strings.Contains(local.String(), "Home") {
+		t.Errorf("Expected the article with its byline, got:\n%s", local.String())
+	}
+}
+
 func TestWeb2MDConverter_NoURL(t *testing.T) {
 	c := NewWeb2MDConverter()
 
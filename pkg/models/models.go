@@ -1,6 +1,9 @@
 package models
 
-import "io"
+import (
+	"fmt"
+	"io"
+)
 
 // ConvertRequest represents a conversion request
 type ConvertRequest struct {
@@ -9,9 +12,49 @@ type ConvertRequest struct {
 	Options map[string]interface{}
 }
 
+// StringOption returns the string option for key, or def if it is unset or not a string
+func (r *ConvertRequest) StringOption(key, def string) string {
+	if v, ok := r.Options[key].(string); ok && v != "" {
+		return v
+	}
+	return def
+}
+
+// IntOption returns the integer option for key, or def if it is unset or not an int
+func (r *ConvertRequest) IntOption(key string, def int) int {
+	if v, ok := r.Options[key].(int); ok {
+		return v
+	}
+	return def
+}
+
+// BoolOption returns the boolean option for key, or def if it is unset or not a bool
+func (r *ConvertRequest) BoolOption(key string, def bool) bool {
+	if v, ok := r.Options[key].(bool); ok {
+		return v
+	}
+	return def
+}
+
 // ConvertResponse represents the result of a conversion
 type ConvertResponse struct {
 	Success  bool
 	Error    error
 	Metadata map[string]string
+	Warnings []Warning
+}
+
+// Warning describes a problem that did not stop a conversion, such as a
+// page that could not be extracted
+type Warning struct {
+	Page    int // page number, 0 if the warning is not about a single page
+	Message string
+}
+
+// String returns the warning prefixed with its page number
+func (w Warning) String() string {
+	if w.Page > 0 {
+		return fmt.Sprintf("page %d: %s", w.Page, w.Message)
+	}
+	return w.Message
 }